@@ -0,0 +1,267 @@
+package byzq
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	// adaptiveWeight is the weight of a new sample in the moving averages
+	// of an AdaptiveStrategy.
+	adaptiveWeight = 0.125
+
+	// concurrentOverhead is the approximate cost of verifying a reply in a
+	// goroutine of its own instead of sequentially. Verifications that are
+	// cheaper than this, e.g. because they are cached, are not worth
+	// verifying concurrently.
+	concurrentOverhead = 10 * time.Microsecond
+
+	// maxFailureRate is the fraction of invalid replies above which replies
+	// are verified as they arrive, since a quorum call then often needs
+	// more than q+1 replies, and the other strategies verify all replies
+	// again for each additional reply.
+	maxFailureRate = 0.05
+)
+
+// AdaptiveStrategy is a ReadStrategy that switches among SequentialVerify,
+// ConcurrentVerifyIndexChan and VerifyLastReplyFirst based on the measured
+// cost of verifying a reply, the time between reply arrivals, and the
+// fraction of replies that fail verification:
+//
+//   - replies with a high failure rate, or that arrive further apart than
+//     they take to verify, are verified as they arrive by
+//     VerifyLastReplyFirst;
+//   - replies that arrive in bursts are verified concurrently once a quorum
+//     is possible, unless verification is too cheap to gain from it or
+//     only one CPU is available, in which case they are verified
+//     sequentially.
+//
+// The strategy is chosen for each Read quorum call when its evaluator is
+// created, and is used for all its replies. Since Evaluate, as used by
+// ReadQF, cannot know whether earlier replies of the slice were evaluated,
+// it uses SequentialVerify instead of VerifyLastReplyFirst.
+//
+// An AdaptiveStrategy adapts to the replicas of a single configuration, and
+// should not be shared by quorum specifications of different configurations.
+// The current choice and the measurements behind it are returned by Choice.
+type AdaptiveStrategy struct {
+	procs int
+
+	mu          sync.Mutex
+	verifyCost  time.Duration // moving average of the cost of a verification
+	replyGap    time.Duration // moving average of the time between replies
+	failureRate float64       // moving average of the fraction of invalid replies
+	costs       uint64        // number of verification cost samples
+	gaps        uint64        // number of reply gap samples
+	failures    uint64        // number of replies sampled for the failure rate
+}
+
+// AdaptiveChoice is the strategy chosen by an AdaptiveStrategy, the reason
+// for choosing it, and the measurements it is based on.
+type AdaptiveChoice struct {
+	Strategy    ReadStrategy
+	Reason      string
+	VerifyCost  time.Duration // average cost of verifying a reply
+	ReplyGap    time.Duration // average time between replies of a quorum call
+	FailureRate float64       // average fraction of replies that are invalid
+	Procs       int           // number of CPUs available for verification
+}
+
+func (c AdaptiveChoice) String() string {
+	return fmt.Sprintf("%v: %s", c.Strategy, c.Reason)
+}
+
+// NewAdaptiveStrategy returns an adaptive strategy with no measurements.
+// It verifies replies sequentially until the cost of a verification has
+// been measured.
+func NewAdaptiveStrategy() *AdaptiveStrategy {
+	return &AdaptiveStrategy{procs: runtime.GOMAXPROCS(0)}
+}
+
+func (a *AdaptiveStrategy) String() string { return "adaptive" }
+
+// Choice returns the strategy currently chosen and the reason for it.
+func (a *AdaptiveStrategy) Choice() AdaptiveChoice {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.choice()
+}
+
+// choice must be called with a.mu held.
+func (a *AdaptiveStrategy) choice() AdaptiveChoice {
+	c := AdaptiveChoice{
+		VerifyCost:  a.verifyCost,
+		ReplyGap:    a.replyGap,
+		FailureRate: a.failureRate,
+		Procs:       a.procs,
+	}
+	switch {
+	case a.costs == 0:
+		c.Strategy = SequentialVerify
+		c.Reason = "the verification cost has not been measured yet"
+	case a.failures > 0 && a.failureRate > maxFailureRate:
+		c.Strategy = VerifyLastReplyFirst
+		c.Reason = fmt.Sprintf("%.1f%% of replies are invalid, so each reply is verified once as it arrives", 100*a.failureRate)
+	case a.gaps > 0 && a.replyGap >= a.verifyCost:
+		c.Strategy = VerifyLastReplyFirst
+		c.Reason = fmt.Sprintf("replies arrive %v apart, longer than the %v verification cost, so each reply is verified while waiting for the next", a.replyGap, a.verifyCost)
+	case a.procs < 2:
+		c.Strategy = SequentialVerify
+		c.Reason = "replies arrive in bursts, but only one CPU is available to verify them"
+	case a.verifyCost < concurrentOverhead:
+		c.Strategy = SequentialVerify
+		c.Reason = fmt.Sprintf("replies arrive in bursts, but the %v verification cost is too low to gain from concurrency", a.verifyCost)
+	default:
+		c.Strategy = ConcurrentVerifyIndexChan
+		c.Reason = fmt.Sprintf("replies arrive in bursts, and the %v verification cost is shared by %d CPUs", a.verifyCost, a.procs)
+	}
+	return c
+}
+
+// movingAverage returns the moving average avg updated with sample, the first
+// sample of which is taken as is.
+func movingAverage(avg, sample float64, samples uint64) float64 {
+	if samples == 0 {
+		return sample
+	}
+	return avg + adaptiveWeight*(sample-avg)
+}
+
+// movingAverageN returns the moving average avg updated with n samples whose
+// mean is sample, as if they were added one by one in random order.
+func movingAverageN(avg, sample float64, n int, samples uint64) float64 {
+	if samples == 0 {
+		return sample
+	}
+	return avg + (1-math.Pow(1-adaptiveWeight, float64(n)))*(sample-avg)
+}
+
+func (a *AdaptiveStrategy) observeGap(gap time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.replyGap = time.Duration(movingAverage(float64(a.replyGap), float64(gap), a.gaps))
+	a.gaps++
+}
+
+// Evaluate evaluates replies with the strategy currently chosen, using
+// SequentialVerify instead of VerifyLastReplyFirst. Since the replies of a
+// quorum call are passed again with each new reply, they are only sampled
+// for the failure rate once they constitute a quorum.
+func (a *AdaptiveStrategy) Evaluate(aq *AuthDataQ, replies []*Value) ReadResult {
+	s := a.Choice().Strategy
+	if s == VerifyLastReplyFirst {
+		s = SequentialVerify
+	}
+	r := a.evaluate(aq, s, replies)
+	if r.Quorum {
+		a.observeFailures(checked(replies), r.Invalid)
+	}
+	return r
+}
+
+// evaluate evaluates replies with s, and measures the cost of the
+// verifications done by s.
+func (a *AdaptiveStrategy) evaluate(aq *AuthDataQ, s ReadStrategy, replies []*Value) ReadResult {
+	// the number of replies verified by s, and the number of them that can
+	// be verified at the same time
+	verified, parallel := 0, 1
+	switch {
+	case s == VerifyLastReplyFirst:
+		if len(replies) > 0 && replies[len(replies)-1].GetC() != nil {
+			verified = 1
+		}
+	case len(replies) > aq.q:
+		for _, reply := range replies {
+			if reply.GetC() != nil {
+				verified++
+			}
+		}
+		if s == ConcurrentVerifyIndexChan {
+			parallel = a.procs
+		}
+	}
+
+	start := time.Now()
+	r := s.Evaluate(aq, replies)
+	elapsed := time.Since(start)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if verified > 0 {
+		// concurrent verifications are done in rounds of parallel replies
+		rounds := (verified + parallel - 1) / parallel
+		a.verifyCost = time.Duration(movingAverage(float64(a.verifyCost), float64(elapsed)/float64(rounds), a.costs))
+		a.costs++
+	}
+	return r
+}
+
+// observeFailures updates the failure rate with n replies, of which invalid
+// failed verification.
+func (a *AdaptiveStrategy) observeFailures(n, invalid int) {
+	if n == 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.failureRate = movingAverageN(a.failureRate, float64(invalid)/float64(n), n, a.failures)
+	a.failures += uint64(n)
+}
+
+// checked returns the number of replies that are not empty, including
+// those removed as invalid.
+func checked(replies []*Value) int {
+	n := 0
+	for _, reply := range replies {
+		if reply == nil || reply.C != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func (a *AdaptiveStrategy) newEvaluator(aq *AuthDataQ) ReadEvaluator {
+	return &adaptiveEvaluator{aq: aq, adaptive: a, strategy: a.Choice().Strategy}
+}
+
+// adaptiveEvaluator evaluates the replies of a quorum call with the strategy
+// chosen when the quorum call started, and measures the time between
+// replies. Each reply is sampled once for the failure rate, once a quorum is
+// possible and the strategies verify the replies.
+type adaptiveEvaluator struct {
+	aq       *AuthDataQ
+	adaptive *AdaptiveStrategy
+	strategy ReadStrategy
+	replies  []*Value
+	last     time.Time
+	sampled  int // replies sampled for the failure rate
+	invalid  int // invalid replies found among them
+}
+
+func (e *adaptiveEvaluator) Add(reply *Value) ReadResult {
+	now := time.Now()
+	if !e.last.IsZero() {
+		e.adaptive.observeGap(now.Sub(e.last))
+	}
+	e.replies = append(e.replies, reply)
+	r := e.adaptive.evaluate(e.aq, e.strategy, e.replies)
+	if len(e.replies) > e.aq.q {
+		n := checked(e.replies[e.sampled:])
+		// invalid replies found among the replies sampled earlier are not
+		// sampled again
+		invalid := r.Invalid - e.invalid
+		if invalid > n {
+			invalid = n
+		} else if invalid < 0 {
+			invalid = 0
+		}
+		e.adaptive.observeFailures(n, invalid)
+		e.sampled, e.invalid = len(e.replies), e.invalid+invalid
+	}
+	// the time spent evaluating is not part of the time between replies
+	e.last = time.Now()
+	return r
+}
@@ -0,0 +1,109 @@
+package byzq
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveChoice(t *testing.T) {
+	const ms = time.Millisecond
+	choiceTests := []struct {
+		name        string
+		procs       int
+		verifyCost  time.Duration
+		replyGap    time.Duration
+		failureRate float64
+		measured    bool
+		want        ReadStrategy
+	}{
+		{"not measured", 4, 0, 0, 0, false, SequentialVerify},
+		{"spaced replies", 4, ms, 2 * ms, 0, true, VerifyLastReplyFirst},
+		{"invalid replies", 4, ms, 0, 0.2, true, VerifyLastReplyFirst},
+		{"burst", 4, ms, 0, 0, true, ConcurrentVerifyIndexChan},
+		{"burst on one CPU", 1, ms, 0, 0, true, SequentialVerify},
+		{"burst of cheap verifications", 4, time.Microsecond, 0, 0, true, SequentialVerify},
+	}
+	for _, test := range choiceTests {
+		t.Run(test.name, func(t *testing.T) {
+			a := NewAdaptiveStrategy()
+			a.procs = test.procs
+			if test.measured {
+				a.verifyCost, a.replyGap, a.failureRate = test.verifyCost, test.replyGap, test.failureRate
+				a.costs, a.gaps, a.failures = 1, 1, 1
+			}
+			c := a.Choice()
+			if c.Strategy != test.want {
+				t.Errorf("got %v, want %v", c, test.want)
+			}
+			if c.Reason == "" {
+				t.Errorf("got no reason for %v", c.Strategy)
+			}
+		})
+	}
+}
+
+func TestAdaptiveMeasurements(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAdaptiveStrategy()
+	qspec.SetReadStrategy(a)
+	v := signContent(t, qspec, "Winnie", "Poo", 1, 0)
+	forged := &Value{C: &Content{Key: "Winnie", Value: "Eeyore", Timestamp: 9}, Algorithm: v.Algorithm, Signature: v.Signature}
+
+	for i := 0; i < 4; i++ {
+		e := qspec.NewReadEvaluator()
+		for _, reply := range []*Value{forged, v, v, v} {
+			if e.Add(reply).Quorum {
+				break
+			}
+		}
+	}
+	c := a.Choice()
+	if c.VerifyCost <= 0 {
+		t.Errorf("got verification cost %v, want > 0", c.VerifyCost)
+	}
+	if c.ReplyGap < 0 {
+		t.Errorf("got reply gap %v, want >= 0", c.ReplyGap)
+	}
+	// one in four replies is invalid
+	if c.FailureRate <= maxFailureRate {
+		t.Errorf("got failure rate %.2f, want > %.2f", c.FailureRate, maxFailureRate)
+	}
+	if c.Strategy != VerifyLastReplyFirst {
+		t.Errorf("got %v, want %v", c, VerifyLastReplyFirst)
+	}
+}
+
+func TestAdaptiveFailureRate(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAdaptiveStrategy()
+	qspec.SetReadStrategy(a)
+	v := signContent(t, qspec, "Winnie", "Poo", 1, 0)
+	forged := &Value{C: &Content{Key: "Winnie", Value: "Eeyore", Timestamp: 9}, Algorithm: v.Algorithm, Signature: v.Signature}
+	replies := []*Value{forged, v, v, v}
+
+	// each reply is sampled once, although the replies are evaluated again
+	// for each new reply
+	e := qspec.NewReadEvaluator()
+	for _, reply := range replies {
+		e.Add(reply)
+	}
+	if a.failures != 4 {
+		t.Errorf("got %d replies sampled by the evaluator, want 4", a.failures)
+	}
+	for i := 1; i <= len(replies); i++ {
+		a.Evaluate(qspec, append([]*Value(nil), replies[:i]...))
+	}
+	if a.failures != 8 {
+		t.Errorf("got %d replies sampled, want 8", a.failures)
+	}
+	// one in four replies is invalid
+	if c := a.Choice(); c.FailureRate < 0.2 || c.FailureRate > 0.3 {
+		t.Errorf("got failure rate %.2f, want about 0.25", c.FailureRate)
+	}
+}
@@ -1,13 +1,9 @@
 package byzq
 
 import (
-	"crypto/ecdsa"
-	"crypto/rand"
-	"crypto/sha256"
+	"crypto"
 	"fmt"
 	"log"
-	"math/big"
-	"sync"
 )
 
 // To generate gorums code for byzq.proto, run 'go generate' in this folder
@@ -16,218 +12,223 @@ import (
 // AuthDataQ is the quorum specification for the Authenticated-Data Byzantine
 // Quorum algorithm described in RSDP, Algorithm 4.15, page 181.
 type AuthDataQ struct {
-	n    int               // size of system
-	f    int               // tolerable number of failures
-	q    int               // quorum size
-	priv *ecdsa.PrivateKey // writer's private key for signing
-	pub  *ecdsa.PublicKey  // public key of the writer (used by readers)
+	n        int             // size of system
+	f        int             // tolerable number of failures
+	q        int             // quorum size
+	signer   Signer          // writer's signer (nil for readers)
+	verifier Verifier        // verifier for the writer's signatures (used by readers)
+	writers  *WriterRegistry // verifiers by writer ID (nil if verifier is used for all writers)
+
+	semantics  Semantics    // register semantics provided by ReadRegister
+	readRepair bool         // repair stale replicas after ReadRegister
+	tieBreak   bool         // choose among equivocating values instead of failing
+	strategy   ReadStrategy // evaluates replies in ReadQF (nil for IncrementalVerify)
+	repairs    uint64       // number of read-repair writes issued (atomic)
 }
 
 // NewAuthDataQ returns a quorum specification or nil and an error
-// if the quorum requirements are not satisfied.
+// if the quorum requirements are not satisfied or the keys are not
+// supported. The private key may be nil for readers.
 // Pre-condition: n>3f and f>0
 // Post-condition:
-func NewAuthDataQ(n int, priv *ecdsa.PrivateKey, pub *ecdsa.PublicKey) (*AuthDataQ, error) {
+func NewAuthDataQ(n int, priv crypto.Signer, pub crypto.PublicKey) (*AuthDataQ, error) {
+	var signer Signer
+	if priv != nil {
+		var err error
+		signer, err = NewSigner(priv)
+		if err != nil {
+			return nil, err
+		}
+	}
+	verifier, err := NewVerifier(pub)
+	if err != nil {
+		return nil, err
+	}
+	return NewAuthDataQFromScheme(n, signer, verifier)
+}
+
+// NewReadOnlyAuthDataQ returns a quorum specification for readers that
+// only holds the writer's public key, or nil and an error if the quorum
+// requirements are not satisfied. The returned specification can verify
+// values, but Sign will fail.
+func NewReadOnlyAuthDataQ(n int, pub crypto.PublicKey) (*AuthDataQ, error) {
+	return NewAuthDataQ(n, nil, pub)
+}
+
+// NewAuthDataQFromScheme returns a quorum specification that signs and
+// verifies values using the provided signature scheme, or nil and an error
+// if the quorum requirements are not satisfied. The signer may be nil for
+// readers.
+func NewAuthDataQFromScheme(n int, signer Signer, verifier Verifier) (*AuthDataQ, error) {
 	f := (n - 1) / 3
 	if f < 1 {
 		return nil, fmt.Errorf("Byzantine quorum require n>3f replicas; only got n=%d, yielding f=%d", n, f)
 	}
-	return &AuthDataQ{n, f, (n + f) / 2, priv, pub}, nil
+	if verifier == nil {
+		return nil, fmt.Errorf("quorum specification requires a verifier")
+	}
+	if signer != nil && signer.Algorithm() != verifier.Algorithm() {
+		return nil, fmt.Errorf("signer algorithm %v does not match verifier algorithm %v", signer.Algorithm(), verifier.Algorithm())
+	}
+	return &AuthDataQ{n: n, f: f, q: (n + f) / 2, signer: signer, verifier: verifier}, nil
 }
 
 // Sign signs the provided content and returns a value to be passed into Write.
 // (This function must currently be exported since our writer client code is not
 // in the byzq package.)
 func (aq *AuthDataQ) Sign(content *Content) (*Value, error) {
+	if aq.signer == nil {
+		return nil, fmt.Errorf("cannot sign content: quorum specification has no private key")
+	}
 	msg, err := content.Marshal()
 	if err != nil {
 		return nil, err
 	}
-	hash := sha256.Sum256(msg)
-	r, s, err := ecdsa.Sign(rand.Reader, aq.priv, hash[:])
+	sig, err := aq.signer.Sign(msg)
 	if err != nil {
 		return nil, err
 	}
-	return &Value{C: content, SignatureR: r.Bytes(), SignatureS: s.Bytes()}, nil
+	return &Value{C: content, Algorithm: aq.signer.Algorithm(), Signature: sig}, nil
 }
 
-func (aq *AuthDataQ) verify(reply *Value) bool {
-	msg, err := reply.C.Marshal()
-	if err != nil {
-		log.Printf("failed to marshal msg for verify: %v", err)
+// Newer reports whether c is ordered after other. Contents are totally
+// ordered by their timestamp, and concurrent writes with equal timestamps are
+// ordered by the writer ID. A nil content is older than any other content.
+func (c *Content) Newer(other *Content) bool {
+	if c == nil {
 		return false
 	}
-	msgHash := sha256.Sum256(msg)
-	r := new(big.Int).SetBytes(reply.SignatureR)
-	s := new(big.Int).SetBytes(reply.SignatureS)
-	return ecdsa.Verify(aq.pub, msgHash[:], r, s)
-}
-
-// ReadQF returns nil and false until the supplied replies
-// constitute a Byzantine quorum, at which point the method returns the
-// single highest value and true.
-func (aq *AuthDataQ) ReadQF(replies []*Value) (*Content, bool) {
-	if len(replies) <= aq.q {
-		// not enough replies yet; need at least bq.q=(n+2f)/2 replies
-		return nil, false
+	if other == nil {
+		return true
 	}
-	var highest *Value
-	for _, reply := range replies {
-		if highest != nil && reply.GetC().GetTimestamp() <= highest.GetC().GetTimestamp() {
-			continue
-		}
-		highest = reply
+	if c.Timestamp != other.Timestamp {
+		return c.Timestamp > other.Timestamp
 	}
-	// returns reply with the highest timestamp, or nil if no replies were verified
-	return highest.GetC(), true
+	return c.WriterID > other.WriterID
 }
 
-// SequentialVerifyReadQF returns nil and false until the supplied replies
-// constitute a Byzantine quorum, at which point the method returns the
-// single highest value and true.
-func (aq *AuthDataQ) SequentialVerifyReadQF(replies []*Value) (*Content, bool) {
-	if len(replies) <= aq.q {
-		// not enough replies yet; need at least bq.q=(n+2f)/2 replies
-		return nil, false
+func (aq *AuthDataQ) verify(reply *Value) bool {
+	if aq.writers == nil {
+		return Verify(aq.verifier, reply)
 	}
-	var highest *Value
-	for _, reply := range replies {
-		if aq.verify(reply) {
-			if highest != nil && reply.C.Timestamp <= highest.C.Timestamp {
-				continue
-			}
-			highest = reply
-		}
+	verifier, found := aq.writers.Verifier(reply.GetC().GetWriterID())
+	if !found {
+		return false
+	}
+	if c := aq.VerifyCache(); c != nil {
+		verifier = cachedWriter{verifier, c}
 	}
-	// returns reply with the highest timestamp, or nil if no replies were verified
-	return highest.C, true
+	return Verify(verifier, reply)
 }
 
-// ConcurrentVerifyWGReadQF returns nil and false until the supplied replies
-// constitute a Byzantine quorum, at which point the method returns the
-// single highest value and true.
-func (aq *AuthDataQ) ConcurrentVerifyWGReadQF(replies []*Value) (*Content, bool) {
-	if len(replies) <= aq.q {
-		// not enough replies yet; need at least bq.q=(n+2f)/2 replies
-		return nil, false
+// Verify reports whether the signature of v is a valid signature of the
+// content of v by the writer whose signatures are verified by verifier.
+// Values without content, or signed with a different algorithm, are never
+// valid. Replicas use Verify to reject forged writes.
+func Verify(verifier Verifier, v *Value) bool {
+	if v.GetC() == nil {
+		// nothing has been signed; replica has no value for this key
+		return false
 	}
-	verified := make([]bool, len(replies))
-	wg := &sync.WaitGroup{}
-	for i, reply := range replies {
-		wg.Add(1)
-		go func(i int, r *Value) {
-			verified[i] = aq.verify(r)
-			wg.Done()
-		}(i, reply)
+	if v.Algorithm != verifier.Algorithm() {
+		return false
 	}
-	wg.Wait()
-	cnt := 0
-	var highest *Value
-	for i, v := range verified {
-		if !v {
-			// some signature could not be verified:
-			cnt++
-			if len(replies)-cnt <= aq.q {
-				return nil, false
-			}
-		}
-		if highest != nil && replies[i].C.Timestamp <= highest.C.Timestamp {
-			continue
-		}
-		highest = replies[i]
+	msg, err := v.C.Marshal()
+	if err != nil {
+		log.Printf("failed to marshal msg for verify: %v", err)
+		return false
 	}
+	return verifier.Verify(msg, v.Signature)
+}
 
-	// returns reply with the highest timestamp, or nil if no replies were verified
-	return highest.C, true
+// ReadQF returns nil and false until more than q of the supplied
+// replies are verified or empty, at which point the method returns the single
+// highest value and true. The replies are evaluated by the read strategy
+// selected with SetReadStrategy, which is IncrementalVerify by default. If no
+// reply holds a valid value, a NoValueError is returned.
+func (aq *AuthDataQ) ReadQF(replies []*Value) (*Content, bool, error) {
+	return aq.readStrategy().Evaluate(aq, replies).QF()
 }
 
-// ConcurrentVerifyIndexChanReadQF returns nil and false until the supplied replies
-// constitute a Byzantine quorum, at which point the method returns the
-// single highest value and true.
-func (aq *AuthDataQ) ConcurrentVerifyIndexChanReadQF(replies []*Value) (*Content, bool) {
-	if len(replies) <= aq.q {
-		// not enough replies yet; need at least bq.q=(n+2f)/2 replies
-		return nil, false
-	}
+// SequentialVerifyReadQF is ReadQF using the SequentialVerify strategy.
+func (aq *AuthDataQ) SequentialVerifyReadQF(replies []*Value) (*Content, bool, error) {
+	return SequentialVerify.Evaluate(aq, replies).QF()
+}
 
-	veriresult := make(chan int, len(replies))
-	for i, reply := range replies {
-		go func(i int, r *Value) {
-			if !aq.verify(r) {
-				i = -1
-			}
-			veriresult <- i
-		}(i, reply)
-	}
+// ConcurrentVerifyWGReadQF is ReadQF using the ConcurrentVerifyWG
+// strategy.
+func (aq *AuthDataQ) ConcurrentVerifyWGReadQF(replies []*Value) (*Content, bool, error) {
+	return ConcurrentVerifyWG.Evaluate(aq, replies).QF()
+}
 
-	cnt := 0
-	var highest *Value
-	for j := 0; j < len(replies); j++ {
-		i := <-veriresult
-		if i == -1 {
-			// some signature could not be verified:
-			cnt++
-			if len(replies)-cnt <= aq.q {
-				return nil, false
-			}
-		}
-		if highest != nil && replies[i].C.Timestamp <= highest.C.Timestamp {
-			continue
-		}
-		highest = replies[i]
-	}
-	// returns reply with the highest timestamp, or nil if no replies were verified
-	return highest.C, true
+// ConcurrentVerifyIndexChanReadQF is ReadQF using the
+// ConcurrentVerifyIndexChan strategy.
+func (aq *AuthDataQ) ConcurrentVerifyIndexChanReadQF(replies []*Value) (*Content, bool, error) {
+	return ConcurrentVerifyIndexChan.Evaluate(aq, replies).QF()
 }
 
-// VerfiyLastReplyFirstReadQF returns nil and false until the supplied replies
-// constitute a Byzantine quorum, at which point the method returns the
-// single highest value and true.
-func (aq *AuthDataQ) VerfiyLastReplyFirstReadQF(replies []*Value) (*Content, bool) {
-	if len(replies) < 1 {
-		return nil, false
-	}
-	if !aq.verify(replies[len(replies)-1]) {
-		// return if last reply failed to verify
-		replies[len(replies)-1] = nil
-		return nil, false
-	}
+// VerfiyLastReplyFirstReadQF is ReadQF using the VerifyLastReplyFirst
+// strategy.
+func (aq *AuthDataQ) VerfiyLastReplyFirstReadQF(replies []*Value) (*Content, bool, error) {
+	return VerifyLastReplyFirst.Evaluate(aq, replies).QF()
+}
+
+// VerifyHighestFirstReadQF is ReadQF using the VerifyHighestFirst
+// strategy.
+func (aq *AuthDataQ) VerifyHighestFirstReadQF(replies []*Value) (*Content, bool, error) {
+	return VerifyHighestFirst.Evaluate(aq, replies).QF()
+}
+
+// PoolVerifyReadQF is ReadQF using the PoolVerify strategy.
+func (aq *AuthDataQ) PoolVerifyReadQF(replies []*Value) (*Content, bool, error) {
+	return PoolVerify.Evaluate(aq, replies).QF()
+}
+
+// ReadTimestampQF returns nil and false until the supplied replies
+// constitute a Byzantine quorum of verified or empty replies, at which point
+// the method returns the content with the highest timestamp and true.
+// An empty reply is sent by a replica that has not yet stored a value for the
+// key; if no reply carries a verified value, the zero timestamp is returned.
+// Writers use the returned timestamp and writer ID to pick the next timestamp.
+func (aq *AuthDataQ) ReadTimestampQF(replies []*Value) (*Content, bool) {
 	if len(replies) <= aq.q {
 		// not enough replies yet; need at least bq.q=(n+2f)/2 replies
 		return nil, false
 	}
-
-	var highest *Value
-	cntnotnil := 0
+	cnt := 0
+	highest := &Content{}
 	for _, reply := range replies {
-		if reply == nil {
+		if reply.GetC() == nil {
+			cnt++
 			continue
 		}
-		cntnotnil++
-		// select reply with highest timestamp
-		if highest != nil && reply.C.Timestamp <= highest.C.Timestamp {
+		if !aq.verify(reply) {
 			continue
 		}
-		highest = reply
+		cnt++
+		if reply.C.Newer(highest) {
+			highest = reply.C
+		}
 	}
-
-	if cntnotnil <= aq.q {
-		// not enough replies yet; need at least bq.q=(n+2f)/2 replies
+	if cnt <= aq.q {
+		// not enough valid replies yet
 		return nil, false
 	}
-	// returns reply with the highest timestamp, or nil if no replies were verified
-	return highest.C, true
+	return highest, true
 }
 
 // WriteQF returns nil and false until it is possible to check for a quorum.
-// If enough replies with the same timestamp is found, we return true.
+// If enough replies acknowledge the timestamp and writer ID of req, we
+// return true. Matching the writer ID as well keeps an acknowledgement of
+// another writer's write with the same timestamp from counting towards the
+// quorum.
 func (aq *AuthDataQ) WriteQF(req *Value, replies []*WriteResponse) (reply *WriteResponse, quorum bool) {
 	if len(replies) <= aq.q {
 		return nil, false
 	}
 	correctReplies := 0
 	for _, r := range replies {
-		if r.Timestamp == req.C.Timestamp {
+		if acknowledges(r, req) {
 			correctReplies++
 			reply = r
 		}
@@ -237,3 +238,8 @@ func (aq *AuthDataQ) WriteQF(req *Value, replies []*WriteResponse) (reply *Write
 	}
 	return reply, true
 }
+
+// acknowledges reports whether r acknowledges the write of req.
+func acknowledges(r *WriteResponse, req *Value) bool {
+	return r.Timestamp == req.C.Timestamp && r.WriterID == req.C.WriterID
+}
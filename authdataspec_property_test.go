@@ -63,8 +63,8 @@ func TestAuthDataQuorumProperties(t *testing.T) {
 	properties.Property("no quorum unless enough replies", prop.ForAll(
 		func(params *qfParams) bool {
 			replies := replyGen(params.quorumSize)
-			reply, byzquorum := params.qspec.ReadQF(replies)
-			return !byzquorum && reply == nil
+			reply, byzquorum, err := params.qspec.ReadQF(replies)
+			return !byzquorum && reply == nil && err == nil
 		},
 		gen.IntRange(4, 200).FlatMap(func(n interface{}) gopter.Gen {
 			qspec, err := NewAuthDataQ(n.(int), priv, &priv.PublicKey)
@@ -85,8 +85,8 @@ func TestAuthDataQuorumProperties(t *testing.T) {
 					t.Fatal("failed to sign message")
 				}
 			}
-			reply, byzquorum := params.qspec.SequentialVerifyReadQF(replies)
-			if !byzquorum {
+			reply, byzquorum, err := params.qspec.SequentialVerifyReadQF(replies)
+			if !byzquorum || err != nil {
 				return false
 			}
 			for _, r := range replies {
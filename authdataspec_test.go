@@ -2,6 +2,8 @@ package byzq
 
 import (
 	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -25,11 +27,11 @@ func TestMain(m *testing.M) {
 	silentLogger := log.New(ioutil.Discard, "", log.LstdFlags)
 	grpclog.SetLogger(silentLogger)
 	grpc.EnableTracing = false
-	var err error
-	priv, err = ParseKey(pemKeyData)
+	key, err := ParseKey(pemKeyData)
 	if err != nil {
 		log.Fatalln("couldn't parse private key")
 	}
+	priv = key.(*ecdsa.PrivateKey)
 	res := m.Run()
 	os.Exit(res)
 }
@@ -208,18 +210,23 @@ func TestAuthDataQ(t *testing.T) {
 
 		qfuncs := []struct {
 			name string
-			qf   func([]*Value) (*Content, bool)
+			qf   func([]*Value) (*Content, bool, error)
 		}{
-			{"(NoSignVerification)ReadQF(4,1)", qspec.ReadQF},
+			{"ReadQF(4,1)", qspec.ReadQF},
 			{"SequentialVerifyReadQFReadQF(4,1)", qspec.SequentialVerifyReadQF},
 			{"ConcurrentVerifyIndexChanReadQF(4,1)", qspec.ConcurrentVerifyIndexChanReadQF},
 			{"VerfiyLastReplyFirstReadQF(4,1)", qspec.VerfiyLastReplyFirstReadQF},
 			{"ConcurrentVerifyWGReadQF(4,1)", qspec.ConcurrentVerifyWGReadQF},
+			{"PoolVerifyReadQF(4,1)", qspec.PoolVerifyReadQF},
+			{"VerifyHighestFirstReadQF(4,1)", qspec.VerifyHighestFirstReadQF},
 		}
 
 		for _, qfunc := range qfuncs {
 			t.Run(fmt.Sprintf("%s %s", qfunc.name, test.name), func(t *testing.T) {
-				reply, byzquorum := qfunc.qf(test.replies)
+				reply, byzquorum, err := qfunc.qf(test.replies)
+				if err != nil {
+					t.Errorf("got error %v, want nil", err)
+				}
 				if byzquorum != test.rq {
 					t.Errorf("got %t, want %t", byzquorum, test.rq)
 				}
@@ -242,6 +249,11 @@ func BenchmarkAuthDataQ(b *testing.B) {
 	if err != nil {
 		b.Error(err)
 	}
+	cached, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		b.Error(err)
+	}
+	cached.SetVerifyCache(1024)
 	for _, test := range authReadQFTests {
 		if !strings.Contains(test.name, "case") {
 			continue
@@ -254,28 +266,216 @@ func BenchmarkAuthDataQ(b *testing.B) {
 		}
 
 		qfuncs := []struct {
-			name string
-			qf   func([]*Value) (*Content, bool)
+			name  string
+			qf    func([]*Value) (*Content, bool, error)
+			cache *VerifyCache
 		}{
-			{"(NoSignVerification)ReadQF(4,1)", qspec.ReadQF},
-			{"SequentialVerifyReadQFReadQF(4,1)", qspec.SequentialVerifyReadQF},
-			{"ConcurrentVerifyIndexChanReadQF(4,1)", qspec.ConcurrentVerifyIndexChanReadQF},
-			{"VerfiyLastReplyFirstReadQF(4,1)", qspec.VerfiyLastReplyFirstReadQF},
-			{"ConcurrentVerifyWGReadQF(4,1)", qspec.ConcurrentVerifyWGReadQF},
+			{"ReadQF(4,1)", qspec.ReadQF, nil},
+			{"SequentialVerifyReadQFReadQF(4,1)", qspec.SequentialVerifyReadQF, nil},
+			{"ConcurrentVerifyIndexChanReadQF(4,1)", qspec.ConcurrentVerifyIndexChanReadQF, nil},
+			{"VerfiyLastReplyFirstReadQF(4,1)", qspec.VerfiyLastReplyFirstReadQF, nil},
+			{"ConcurrentVerifyWGReadQF(4,1)", qspec.ConcurrentVerifyWGReadQF, nil},
+			{"PoolVerifyReadQF(4,1)", qspec.PoolVerifyReadQF, nil},
+			{"VerifyHighestFirstReadQF(4,1)", qspec.VerifyHighestFirstReadQF, nil},
+			{"CachedSequentialVerifyReadQF(4,1)", cached.SequentialVerifyReadQF, cached.VerifyCache()},
+			{"CachedConcurrentVerifyIndexChanReadQF(4,1)", cached.ConcurrentVerifyIndexChanReadQF, cached.VerifyCache()},
+			{"CachedVerfiyLastReplyFirstReadQF(4,1)", cached.VerfiyLastReplyFirstReadQF, cached.VerifyCache()},
+			{"CachedConcurrentVerifyWGReadQF(4,1)", cached.ConcurrentVerifyWGReadQF, cached.VerifyCache()},
+			{"CachedPoolVerifyReadQF(4,1)", cached.PoolVerifyReadQF, cached.VerifyCache()},
+			{"CachedVerifyHighestFirstReadQF(4,1)", cached.VerifyHighestFirstReadQF, cached.VerifyCache()},
 		}
 
 		for _, qfunc := range qfuncs {
 			b.Run(fmt.Sprintf("%s %s", qfunc.name, test.name), func(b *testing.B) {
+				var before VerifyCacheStats
+				if qfunc.cache != nil {
+					before = qfunc.cache.Stats()
+				}
 				b.ReportAllocs()
 				b.ResetTimer()
 				for i := 0; i < b.N; i++ {
 					qfunc.qf(test.replies)
 				}
+				if qfunc.cache != nil {
+					after := qfunc.cache.Stats()
+					delta := VerifyCacheStats{Hits: after.Hits - before.Hits, Misses: after.Misses - before.Misses}
+					b.ReportMetric(100*delta.HitRate(), "hit%")
+				}
 			})
 		}
 	}
 }
 
+var contentNewerTests = []struct {
+	name  string
+	a, b  *Content
+	newer bool
+}{
+	{"nil/nil", nil, nil, false},
+	{"nil/content", nil, &Content{}, false},
+	{"content/nil", &Content{}, nil, true},
+	{"higher ts", &Content{Timestamp: 2}, &Content{Timestamp: 1, WriterID: 5}, true},
+	{"lower ts", &Content{Timestamp: 1, WriterID: 5}, &Content{Timestamp: 2}, false},
+	{"equal ts higher writer", &Content{Timestamp: 2, WriterID: 2}, &Content{Timestamp: 2, WriterID: 1}, true},
+	{"equal ts lower writer", &Content{Timestamp: 2, WriterID: 1}, &Content{Timestamp: 2, WriterID: 2}, false},
+	{"equal", &Content{Timestamp: 2, WriterID: 1}, &Content{Timestamp: 2, WriterID: 1}, false},
+}
+
+func TestContentNewer(t *testing.T) {
+	for _, test := range contentNewerTests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.a.Newer(test.b); got != test.newer {
+				t.Errorf("got %t, want %t", got, test.newer)
+			}
+		})
+	}
+}
+
+var (
+	w1Val = &Value{C: &Content{Key: "Winnie", Value: "Poo", Timestamp: 2, WriterID: 1}}
+	w2Val = &Value{C: &Content{Key: "Winnie", Value: "Tigger", Timestamp: 2, WriterID: 2}}
+	empty = &Value{}
+)
+
+var authReadTimestampQFTests = []struct {
+	name     string
+	replies  []*Value
+	expected *Content
+	rq       bool
+}{
+	{
+		"nil input",
+		nil,
+		nil,
+		false,
+	},
+	{
+		"no quorum",
+		[]*Value{myVal, myVal},
+		nil,
+		false,
+	},
+	{
+		"quorum of empty replies",
+		[]*Value{empty, empty, empty},
+		&Content{},
+		true,
+	},
+	{
+		"quorum with some empty replies",
+		[]*Value{empty, myVal2, empty},
+		myVal2.C,
+		true,
+	},
+	{
+		"quorum (I)",
+		[]*Value{myVal, myVal3, myVal2},
+		myVal3.C,
+		true,
+	},
+	{
+		"concurrent writers",
+		[]*Value{w1Val, w2Val, myVal},
+		w2Val.C,
+		true,
+	},
+}
+
+func TestAuthDataQReadTimestamp(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Error(err)
+	}
+	for _, test := range authReadTimestampQFTests {
+		t.Run(fmt.Sprintf("ReadTimestampQF(4,1) %s", test.name), func(t *testing.T) {
+			replies := make([]*Value, len(test.replies))
+			for i, r := range test.replies {
+				if r.C == nil {
+					replies[i] = r
+					continue
+				}
+				replies[i], err = qspec.Sign(r.C)
+				if err != nil {
+					t.Fatal("Failed to sign message")
+				}
+			}
+			reply, byzquorum := qspec.ReadTimestampQF(replies)
+			if byzquorum != test.rq {
+				t.Errorf("got %t, want %t", byzquorum, test.rq)
+			}
+			if !reply.Equal(test.expected) {
+				t.Errorf("got %v, want %v as quorum reply", reply, test.expected)
+			}
+		})
+	}
+
+	t.Run("ReadTimestampQF(4,1) invalid signatures", func(t *testing.T) {
+		replies := make([]*Value, 3)
+		for i := range replies {
+			replies[i], err = qspec.Sign(myVal4.C)
+			if err != nil {
+				t.Fatal("Failed to sign message")
+			}
+		}
+		// forge a higher timestamp on one reply
+		replies[0] = &Value{C: &Content{Key: "Winnie", Timestamp: 100}, Signature: replies[0].Signature}
+		if reply, byzquorum := qspec.ReadTimestampQF(replies); byzquorum {
+			t.Errorf("got quorum with reply %v, want no quorum", reply)
+		}
+		replies = append(replies, replies[1])
+		reply, byzquorum := qspec.ReadTimestampQF(replies)
+		if !byzquorum {
+			t.Fatalf("got %t, want %t", byzquorum, true)
+		}
+		if !reply.Equal(myVal4.C) {
+			t.Errorf("got %v, want %v as quorum reply", reply, myVal4.C)
+		}
+	})
+}
+
+func TestVerify(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := qspec.Sign(myVal.C)
+	if err != nil {
+		t.Fatal("Failed to sign message")
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherVerifier, err := NewVerifier(&other.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := *signed
+	tampered.C = &Content{Key: myVal.C.Key, Value: "Tigger", Timestamp: myVal.C.Timestamp}
+	wrongAlg := *signed
+	wrongAlg.Algorithm = ED25519
+	verifyTests := []struct {
+		name     string
+		verifier Verifier
+		v        *Value
+		valid    bool
+	}{
+		{"valid", qspec.verifier, signed, true},
+		{"wrong key", otherVerifier, signed, false},
+		{"tampered content", qspec.verifier, &tampered, false},
+		{"wrong algorithm", qspec.verifier, &wrongAlg, false},
+		{"no content", qspec.verifier, &Value{Signature: signed.Signature}, false},
+		{"nil value", qspec.verifier, nil, false},
+	}
+	for _, test := range verifyTests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Verify(test.verifier, test.v); got != test.valid {
+				t.Errorf("got %t, want %t", got, test.valid)
+			}
+		})
+	}
+}
+
 var authWriteQFTests = []struct {
 	name     string
 	replies  []*WriteResponse
@@ -421,6 +621,28 @@ func TestAuthDataQW(t *testing.T) {
 	}
 }
 
+func TestAuthDataQWOtherWriter(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &Value{C: &Content{Timestamp: 1, WriterID: 1}}
+	// acknowledgements of another writer's write with the same timestamp
+	replies := []*WriteResponse{
+		{Timestamp: 1, WriterID: 1},
+		{Timestamp: 1, WriterID: 1},
+		{Timestamp: 1, WriterID: 2},
+		{Timestamp: 1, WriterID: 2},
+	}
+	if reply, quorum := qspec.WriteQF(req, replies); quorum {
+		t.Errorf("got %v, %t, want no quorum", reply, quorum)
+	}
+	replies = append(replies, &WriteResponse{Timestamp: 1, WriterID: 1})
+	if reply, quorum := qspec.WriteQF(req, replies); !quorum || reply.WriterID != 1 {
+		t.Errorf("got %v, %t, want acknowledgement of writer 1, true", reply, quorum)
+	}
+}
+
 func BenchmarkAuthDataQW(b *testing.B) {
 	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
 	if err != nil {
@@ -21,6 +21,8 @@ import math "math"
 import _ "github.com/relab/gorums"
 import _ "github.com/gogo/protobuf/gogoproto"
 
+import strconv "strconv"
+
 import bytes "bytes"
 
 import binary "encoding/binary"
@@ -52,6 +54,27 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.GoGoProtoPackageIsVersion2 // please upgrade the proto package
 
+type Algorithm int32
+
+const (
+	ECDSA_P256 Algorithm = 0
+	ECDSA_P384 Algorithm = 1
+	ED25519    Algorithm = 2
+)
+
+var Algorithm_name = map[int32]string{
+	0: "ECDSA_P256",
+	1: "ECDSA_P384",
+	2: "ED25519",
+}
+var Algorithm_value = map[string]int32{
+	"ECDSA_P256": 0,
+	"ECDSA_P384": 1,
+	"ED25519":    2,
+}
+
+func (Algorithm) EnumDescriptor() ([]byte, []int) { return fileDescriptorByzq, []int{0} }
+
 // [Read, requestID]
 type Key struct {
 	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
@@ -72,6 +95,7 @@ type Content struct {
 	Key       string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
 	Timestamp int64  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 	Value     string `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	WriterID  uint32 `protobuf:"varint,4,opt,name=writerID,proto3" json:"writerID,omitempty"`
 }
 
 func (m *Content) Reset()                    { *m = Content{} }
@@ -99,12 +123,19 @@ func (m *Content) GetValue() string {
 	return ""
 }
 
+func (m *Content) GetWriterID() uint32 {
+	if m != nil {
+		return m.WriterID
+	}
+	return 0
+}
+
 // [Value, requestID, ts, val, signature]
 // [Write, wts, val, signature]
 type Value struct {
-	C          *Content `protobuf:"bytes,1,opt,name=c" json:"c,omitempty"`
-	SignatureR []byte   `protobuf:"bytes,2,opt,name=signatureR,proto3" json:"signatureR,omitempty"`
-	SignatureS []byte   `protobuf:"bytes,3,opt,name=signatureS,proto3" json:"signatureS,omitempty"`
+	C         *Content  `protobuf:"bytes,1,opt,name=c" json:"c,omitempty"`
+	Algorithm Algorithm `protobuf:"varint,4,opt,name=algorithm,proto3,enum=byzq.Algorithm" json:"algorithm,omitempty"`
+	Signature []byte    `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"`
 }
 
 func (m *Value) Reset()                    { *m = Value{} }
@@ -118,23 +149,24 @@ func (m *Value) GetC() *Content {
 	return nil
 }
 
-func (m *Value) GetSignatureR() []byte {
+func (m *Value) GetAlgorithm() Algorithm {
 	if m != nil {
-		return m.SignatureR
+		return m.Algorithm
 	}
-	return nil
+	return ECDSA_P256
 }
 
-func (m *Value) GetSignatureS() []byte {
+func (m *Value) GetSignature() []byte {
 	if m != nil {
-		return m.SignatureS
+		return m.Signature
 	}
 	return nil
 }
 
-// [Ack, ts]
+// [Ack, ts, writerID]
 type WriteResponse struct {
-	Timestamp int64 `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Timestamp int64  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	WriterID  uint32 `protobuf:"varint,2,opt,name=writerID,proto3" json:"writerID,omitempty"`
 }
 
 func (m *WriteResponse) Reset()                    { *m = WriteResponse{} }
@@ -148,11 +180,26 @@ func (m *WriteResponse) GetTimestamp() int64 {
 	return 0
 }
 
+func (m *WriteResponse) GetWriterID() uint32 {
+	if m != nil {
+		return m.WriterID
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*Key)(nil), "byzq.Key")
 	proto.RegisterType((*Content)(nil), "byzq.Content")
 	proto.RegisterType((*Value)(nil), "byzq.Value")
 	proto.RegisterType((*WriteResponse)(nil), "byzq.WriteResponse")
+	proto.RegisterEnum("byzq.Algorithm", Algorithm_name, Algorithm_value)
+}
+func (x Algorithm) String() string {
+	s, ok := Algorithm_name[int32(x)]
+	if ok {
+		return s
+	}
+	return strconv.Itoa(int(x))
 }
 func (this *Key) Equal(that interface{}) bool {
 	if that == nil {
@@ -218,6 +265,9 @@ func (this *Content) Equal(that interface{}) bool {
 	if this.Value != that1.Value {
 		return false
 	}
+	if this.WriterID != that1.WriterID {
+		return false
+	}
 	return true
 }
 func (this *Value) Equal(that interface{}) bool {
@@ -248,10 +298,10 @@ func (this *Value) Equal(that interface{}) bool {
 	if !this.C.Equal(that1.C) {
 		return false
 	}
-	if !bytes.Equal(this.SignatureR, that1.SignatureR) {
+	if this.Algorithm != that1.Algorithm {
 		return false
 	}
-	if !bytes.Equal(this.SignatureS, that1.SignatureS) {
+	if !bytes.Equal(this.Signature, that1.Signature) {
 		return false
 	}
 	return true
@@ -284,6 +334,9 @@ func (this *WriteResponse) Equal(that interface{}) bool {
 	if this.Timestamp != that1.Timestamp {
 		return false
 	}
+	if this.WriterID != that1.WriterID {
+		return false
+	}
 	return true
 }
 
@@ -307,19 +360,19 @@ type internalWriteResponse struct {
 
 /* Code generated by protoc-gen-gorums - template source file: calltype_quorumcall.tmpl */
 
-/* Exported types and methods for quorum call method Read */
+/* Exported types and methods for quorum call method Write */
 
-// Read is invoked as a quorum call on all nodes in configuration c,
+// Write is invoked as a quorum call on all nodes in configuration c,
 // using the same argument arg, and returns the result.
-func (c *Configuration) Read(ctx context.Context, arg *Key) (*Content, error) {
-	return c.read(ctx, arg)
+func (c *Configuration) Write(ctx context.Context, arg *Value) (*WriteResponse, error) {
+	return c.write(ctx, arg)
 }
 
-/* Unexported quorum call method Read */
-func (c *Configuration) read(ctx context.Context, a *Key) (resp *Content, err error) {
+/* Unexported quorum call method Write */
+func (c *Configuration) write(ctx context.Context, a *Value) (resp *WriteResponse, err error) {
 	var ti traceInfo
 	if c.mgr.opts.trace {
-		ti.Trace = trace.New("gorums."+c.tstring()+".Sent", "Read")
+		ti.Trace = trace.New("gorums."+c.tstring()+".Sent", "Write")
 		defer ti.Finish()
 
 		ti.firstLine.cid = c.id
@@ -341,13 +394,13 @@ func (c *Configuration) read(ctx context.Context, a *Key) (resp *Content, err er
 	}
 
 	expected := c.n
-	replyChan := make(chan internalValue, expected)
+	replyChan := make(chan internalWriteResponse, expected)
 	for _, n := range c.nodes {
-		go callGRPCRead(ctx, n, a, replyChan)
+		go callGRPCWrite(ctx, n, a, replyChan)
 	}
 
 	var (
-		replyValues = make([]*Value, 0, expected)
+		replyValues = make([]*WriteResponse, 0, expected)
 		errCount    int
 		quorum      bool
 	)
@@ -363,7 +416,7 @@ func (c *Configuration) read(ctx context.Context, a *Key) (resp *Content, err er
 				ti.LazyLog(&payload{sent: false, id: r.nid, msg: r.reply}, false)
 			}
 			replyValues = append(replyValues, r.reply)
-			if resp, quorum = c.qspec.ReadQF(replyValues); quorum {
+			if resp, quorum = c.qspec.WriteQF(a, replyValues); quorum {
 				return resp, nil
 			}
 		case <-ctx.Done():
@@ -376,12 +429,12 @@ func (c *Configuration) read(ctx context.Context, a *Key) (resp *Content, err er
 	}
 }
 
-func callGRPCRead(ctx context.Context, node *Node, arg *Key, replyChan chan<- internalValue) {
-	reply := new(Value)
+func callGRPCWrite(ctx context.Context, node *Node, arg *Value, replyChan chan<- internalWriteResponse) {
+	reply := new(WriteResponse)
 	start := time.Now()
 	err := grpc.Invoke(
 		ctx,
-		"/byzq.Storage/Read",
+		"/byzq.Storage/Write",
 		arg,
 		reply,
 		node.conn,
@@ -392,22 +445,22 @@ func callGRPCRead(ctx context.Context, node *Node, arg *Key, replyChan chan<- in
 	} else {
 		node.setLastErr(err)
 	}
-	replyChan <- internalValue{node.id, reply, err}
+	replyChan <- internalWriteResponse{node.id, reply, err}
 }
 
-/* Exported types and methods for quorum call method Write */
+/* Exported types and methods for quorum call method ReadTimestamp */
 
-// Write is invoked as a quorum call on all nodes in configuration c,
+// ReadTimestamp is invoked as a quorum call on all nodes in configuration c,
 // using the same argument arg, and returns the result.
-func (c *Configuration) Write(ctx context.Context, arg *Value) (*WriteResponse, error) {
-	return c.write(ctx, arg)
+func (c *Configuration) ReadTimestamp(ctx context.Context, arg *Key) (*Content, error) {
+	return c.readTimestamp(ctx, arg)
 }
 
-/* Unexported quorum call method Write */
-func (c *Configuration) write(ctx context.Context, a *Value) (resp *WriteResponse, err error) {
+/* Unexported quorum call method ReadTimestamp */
+func (c *Configuration) readTimestamp(ctx context.Context, a *Key) (resp *Content, err error) {
 	var ti traceInfo
 	if c.mgr.opts.trace {
-		ti.Trace = trace.New("gorums."+c.tstring()+".Sent", "Write")
+		ti.Trace = trace.New("gorums."+c.tstring()+".Sent", "ReadTimestamp")
 		defer ti.Finish()
 
 		ti.firstLine.cid = c.id
@@ -429,13 +482,13 @@ func (c *Configuration) write(ctx context.Context, a *Value) (resp *WriteRespons
 	}
 
 	expected := c.n
-	replyChan := make(chan internalWriteResponse, expected)
+	replyChan := make(chan internalValue, expected)
 	for _, n := range c.nodes {
-		go callGRPCWrite(ctx, n, a, replyChan)
+		go callGRPCReadTimestamp(ctx, n, a, replyChan)
 	}
 
 	var (
-		replyValues = make([]*WriteResponse, 0, expected)
+		replyValues = make([]*Value, 0, expected)
 		errCount    int
 		quorum      bool
 	)
@@ -451,7 +504,7 @@ func (c *Configuration) write(ctx context.Context, a *Value) (resp *WriteRespons
 				ti.LazyLog(&payload{sent: false, id: r.nid, msg: r.reply}, false)
 			}
 			replyValues = append(replyValues, r.reply)
-			if resp, quorum = c.qspec.WriteQF(a, replyValues); quorum {
+			if resp, quorum = c.qspec.ReadTimestampQF(replyValues); quorum {
 				return resp, nil
 			}
 		case <-ctx.Done():
@@ -464,12 +517,12 @@ func (c *Configuration) write(ctx context.Context, a *Value) (resp *WriteRespons
 	}
 }
 
-func callGRPCWrite(ctx context.Context, node *Node, arg *Value, replyChan chan<- internalWriteResponse) {
-	reply := new(WriteResponse)
+func callGRPCReadTimestamp(ctx context.Context, node *Node, arg *Key, replyChan chan<- internalValue) {
+	reply := new(Value)
 	start := time.Now()
 	err := grpc.Invoke(
 		ctx,
-		"/byzq.Storage/Write",
+		"/byzq.Storage/ReadTimestamp",
 		arg,
 		reply,
 		node.conn,
@@ -480,7 +533,7 @@ func callGRPCWrite(ctx context.Context, node *Node, arg *Value, replyChan chan<-
 	} else {
 		node.setLastErr(err)
 	}
-	replyChan <- internalWriteResponse{node.id, reply, err}
+	replyChan <- internalValue{node.id, reply, err}
 }
 
 /* Code generated by protoc-gen-gorums - template source file: node.tmpl */
@@ -528,13 +581,13 @@ func (n *Node) close() error {
 
 // QuorumSpec is the interface that wraps every quorum function.
 type QuorumSpec interface {
-	// ReadQF is the quorum function for the Read
-	// quorum call method.
-	ReadQF(replies []*Value) (*Content, bool)
-
 	// WriteQF is the quorum function for the Write
 	// quorum call method.
 	WriteQF(req *Value, replies []*WriteResponse) (*WriteResponse, bool)
+
+	// ReadTimestampQF is the quorum function for the ReadTimestamp
+	// quorum call method.
+	ReadTimestampQF(replies []*Value) (*Content, bool)
 }
 
 /* Static resources */
@@ -1161,6 +1214,7 @@ const _ = grpc.SupportPackageIsVersion4
 type StorageClient interface {
 	Read(ctx context.Context, in *Key, opts ...grpc.CallOption) (*Value, error)
 	Write(ctx context.Context, in *Value, opts ...grpc.CallOption) (*WriteResponse, error)
+	ReadTimestamp(ctx context.Context, in *Key, opts ...grpc.CallOption) (*Value, error)
 }
 
 type storageClient struct {
@@ -1189,11 +1243,21 @@ func (c *storageClient) Write(ctx context.Context, in *Value, opts ...grpc.CallO
 	return out, nil
 }
 
+func (c *storageClient) ReadTimestamp(ctx context.Context, in *Key, opts ...grpc.CallOption) (*Value, error) {
+	out := new(Value)
+	err := grpc.Invoke(ctx, "/byzq.Storage/ReadTimestamp", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Storage service
 
 type StorageServer interface {
 	Read(context.Context, *Key) (*Value, error)
 	Write(context.Context, *Value) (*WriteResponse, error)
+	ReadTimestamp(context.Context, *Key) (*Value, error)
 }
 
 func RegisterStorageServer(s *grpc.Server, srv StorageServer) {
@@ -1236,6 +1300,24 @@ func _Storage_Write_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Storage_ReadTimestamp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Key)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).ReadTimestamp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/byzq.Storage/ReadTimestamp",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).ReadTimestamp(ctx, req.(*Key))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Storage_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "byzq.Storage",
 	HandlerType: (*StorageServer)(nil),
@@ -1248,6 +1330,10 @@ var _Storage_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Write",
 			Handler:    _Storage_Write_Handler,
 		},
+		{
+			MethodName: "ReadTimestamp",
+			Handler:    _Storage_ReadTimestamp_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "byzq.proto",
@@ -1309,6 +1395,11 @@ func (m *Content) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintByzq(dAtA, i, uint64(len(m.Value)))
 		i += copy(dAtA[i:], m.Value)
 	}
+	if m.WriterID != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintByzq(dAtA, i, uint64(m.WriterID))
+	}
 	return i, nil
 }
 
@@ -1337,17 +1428,16 @@ func (m *Value) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i += n1
 	}
-	if len(m.SignatureR) > 0 {
-		dAtA[i] = 0x12
+	if m.Algorithm != 0 {
+		dAtA[i] = 0x20
 		i++
-		i = encodeVarintByzq(dAtA, i, uint64(len(m.SignatureR)))
-		i += copy(dAtA[i:], m.SignatureR)
+		i = encodeVarintByzq(dAtA, i, uint64(m.Algorithm))
 	}
-	if len(m.SignatureS) > 0 {
-		dAtA[i] = 0x1a
+	if len(m.Signature) > 0 {
+		dAtA[i] = 0x2a
 		i++
-		i = encodeVarintByzq(dAtA, i, uint64(len(m.SignatureS)))
-		i += copy(dAtA[i:], m.SignatureS)
+		i = encodeVarintByzq(dAtA, i, uint64(len(m.Signature)))
+		i += copy(dAtA[i:], m.Signature)
 	}
 	return i, nil
 }
@@ -1372,6 +1462,11 @@ func (m *WriteResponse) MarshalTo(dAtA []byte) (int, error) {
 		i++
 		i = encodeVarintByzq(dAtA, i, uint64(m.Timestamp))
 	}
+	if m.WriterID != 0 {
+		dAtA[i] = 0x10
+		i++
+		i = encodeVarintByzq(dAtA, i, uint64(m.WriterID))
+	}
 	return i, nil
 }
 
@@ -1408,6 +1503,9 @@ func (m *Content) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovByzq(uint64(l))
 	}
+	if m.WriterID != 0 {
+		n += 1 + sovByzq(uint64(m.WriterID))
+	}
 	return n
 }
 
@@ -1418,11 +1516,10 @@ func (m *Value) Size() (n int) {
 		l = m.C.Size()
 		n += 1 + l + sovByzq(uint64(l))
 	}
-	l = len(m.SignatureR)
-	if l > 0 {
-		n += 1 + l + sovByzq(uint64(l))
+	if m.Algorithm != 0 {
+		n += 1 + sovByzq(uint64(m.Algorithm))
 	}
-	l = len(m.SignatureS)
+	l = len(m.Signature)
 	if l > 0 {
 		n += 1 + l + sovByzq(uint64(l))
 	}
@@ -1435,6 +1532,9 @@ func (m *WriteResponse) Size() (n int) {
 	if m.Timestamp != 0 {
 		n += 1 + sovByzq(uint64(m.Timestamp))
 	}
+	if m.WriterID != 0 {
+		n += 1 + sovByzq(uint64(m.WriterID))
+	}
 	return n
 }
 
@@ -1469,6 +1569,7 @@ func (this *Content) String() string {
 		`Key:` + fmt.Sprintf("%v", this.Key) + `,`,
 		`Timestamp:` + fmt.Sprintf("%v", this.Timestamp) + `,`,
 		`Value:` + fmt.Sprintf("%v", this.Value) + `,`,
+		`WriterID:` + fmt.Sprintf("%v", this.WriterID) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -1479,8 +1580,8 @@ func (this *Value) String() string {
 	}
 	s := strings.Join([]string{`&Value{`,
 		`C:` + strings.Replace(fmt.Sprintf("%v", this.C), "Content", "Content", 1) + `,`,
-		`SignatureR:` + fmt.Sprintf("%v", this.SignatureR) + `,`,
-		`SignatureS:` + fmt.Sprintf("%v", this.SignatureS) + `,`,
+		`Algorithm:` + fmt.Sprintf("%v", this.Algorithm) + `,`,
+		`Signature:` + fmt.Sprintf("%v", this.Signature) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -1491,6 +1592,7 @@ func (this *WriteResponse) String() string {
 	}
 	s := strings.Join([]string{`&WriteResponse{`,
 		`Timestamp:` + fmt.Sprintf("%v", this.Timestamp) + `,`,
+		`WriterID:` + fmt.Sprintf("%v", this.WriterID) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -1688,6 +1790,25 @@ func (m *Content) Unmarshal(dAtA []byte) error {
 			}
 			m.Value = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field WriterID", wireType)
+			}
+			m.WriterID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowByzq
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.WriterID |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipByzq(dAtA[iNdEx:])
@@ -1771,11 +1892,11 @@ func (m *Value) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
-		case 2:
-			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SignatureR", wireType)
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Algorithm", wireType)
 			}
-			var byteLen int
+			m.Algorithm = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowByzq
@@ -1785,26 +1906,14 @@ func (m *Value) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				m.Algorithm |= (Algorithm(b) & 0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if byteLen < 0 {
-				return ErrInvalidLengthByzq
-			}
-			postIndex := iNdEx + byteLen
-			if postIndex > l {
-				return io.ErrUnexpectedEOF
-			}
-			m.SignatureR = append(m.SignatureR[:0], dAtA[iNdEx:postIndex]...)
-			if m.SignatureR == nil {
-				m.SignatureR = []byte{}
-			}
-			iNdEx = postIndex
-		case 3:
+		case 5:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field SignatureS", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Signature", wireType)
 			}
 			var byteLen int
 			for shift := uint(0); ; shift += 7 {
@@ -1828,9 +1937,9 @@ func (m *Value) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.SignatureS = append(m.SignatureS[:0], dAtA[iNdEx:postIndex]...)
-			if m.SignatureS == nil {
-				m.SignatureS = []byte{}
+			m.Signature = append(m.Signature[:0], dAtA[iNdEx:postIndex]...)
+			if m.Signature == nil {
+				m.Signature = []byte{}
 			}
 			iNdEx = postIndex
 		default:
@@ -1902,6 +2011,25 @@ func (m *WriteResponse) Unmarshal(dAtA []byte) error {
 					break
 				}
 			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field WriterID", wireType)
+			}
+			m.WriterID = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowByzq
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.WriterID |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipByzq(dAtA[iNdEx:])
@@ -2031,27 +2159,34 @@ var (
 func init() { proto.RegisterFile("byzq.proto", fileDescriptorByzq) }
 
 var fileDescriptorByzq = []byte{
-	// 350 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x64, 0x51, 0xc1, 0x4e, 0xea, 0x40,
-	0x14, 0xed, 0x7d, 0x85, 0xc7, 0xe3, 0xf2, 0xc8, 0x7b, 0x19, 0x4d, 0x6c, 0xaa, 0x99, 0x90, 0xc6,
-	0x05, 0x1b, 0x68, 0x02, 0x7f, 0xa0, 0x4b, 0x16, 0x26, 0x43, 0xa2, 0xeb, 0x16, 0xc6, 0xda, 0x48,
-	0x3b, 0xd8, 0x4e, 0x4d, 0xea, 0x8a, 0x4f, 0xf0, 0x33, 0xf8, 0x01, 0x7e, 0xc0, 0x95, 0x4b, 0x96,
-	0x2e, 0x65, 0xdc, 0xb8, 0x34, 0xf1, 0x07, 0x4c, 0xa7, 0x44, 0x8b, 0xae, 0xe6, 0x9c, 0x7b, 0xcf,
-	0xbd, 0xe7, 0xcc, 0x0c, 0xa2, 0x9f, 0xdf, 0xdd, 0xf4, 0xe7, 0x89, 0x90, 0x82, 0xd4, 0x0a, 0x6c,
-	0x1f, 0x07, 0xa1, 0xbc, 0xca, 0xfc, 0xfe, 0x44, 0x44, 0x6e, 0xc2, 0x67, 0x9e, 0xef, 0x06, 0x22,
-	0xc9, 0xa2, 0x74, 0x7b, 0x94, 0x5a, 0xbb, 0x57, 0x51, 0x05, 0x22, 0x10, 0xae, 0x2e, 0xfb, 0xd9,
-	0xa5, 0x66, 0x9a, 0x68, 0x54, 0xca, 0x9d, 0x03, 0x34, 0x47, 0x3c, 0x27, 0xff, 0xd1, 0xbc, 0xe6,
-	0xb9, 0x05, 0x1d, 0xe8, 0x36, 0x59, 0x01, 0x9d, 0x33, 0x6c, 0x9c, 0x8a, 0x58, 0xf2, 0x58, 0xfe,
-	0x6c, 0x92, 0x23, 0x6c, 0xca, 0x30, 0xe2, 0xa9, 0xf4, 0xa2, 0xb9, 0xf5, 0xab, 0x03, 0x5d, 0x93,
-	0x7d, 0x15, 0xc8, 0x3e, 0xd6, 0x6f, 0xbd, 0x59, 0xc6, 0x2d, 0x53, 0x4f, 0x94, 0xc4, 0x99, 0x62,
-	0xfd, 0xbc, 0x00, 0xe4, 0x10, 0x61, 0xa2, 0x97, 0xb5, 0x06, 0xed, 0xbe, 0xbe, 0xe5, 0xd6, 0x88,
-	0xc1, 0x84, 0x50, 0xc4, 0x34, 0x0c, 0x62, 0x4f, 0x66, 0x09, 0x67, 0x7a, 0xf5, 0x5f, 0x56, 0xa9,
-	0xec, 0xf4, 0xc7, 0xda, 0xa0, 0xda, 0x1f, 0x3b, 0x3d, 0x6c, 0x5f, 0x24, 0xa1, 0xe4, 0x8c, 0xa7,
-	0x73, 0x11, 0xa7, 0x7c, 0x37, 0x2a, 0x7c, 0x8b, 0x3a, 0x10, 0xd8, 0x18, 0x4b, 0x91, 0x78, 0x01,
-	0x27, 0x2e, 0xd6, 0x18, 0xf7, 0xa6, 0xa4, 0x59, 0x66, 0x1a, 0xf1, 0xdc, 0x6e, 0x95, 0x50, 0xc7,
-	0x76, 0xfe, 0x2d, 0x56, 0x16, 0x3c, 0xbc, 0x5b, 0x9f, 0xcf, 0x32, 0xc4, 0xba, 0xb6, 0x22, 0x55,
-	0x99, 0xbd, 0x57, 0x92, 0x9d, 0x10, 0xce, 0x9f, 0x62, 0x76, 0xb9, 0xb2, 0xe0, 0xa4, 0xbb, 0xde,
-	0x50, 0xe3, 0x69, 0x43, 0x8d, 0x85, 0xa2, 0xb0, 0x54, 0x14, 0x1e, 0x15, 0x85, 0xb5, 0xa2, 0xf0,
-	0xac, 0x28, 0xbc, 0x2a, 0x6a, 0xbc, 0x29, 0x0a, 0xf7, 0x2f, 0xd4, 0xf0, 0x7f, 0xeb, 0x0f, 0x1a,
-	0x7e, 0x04, 0x00, 0x00, 0xff, 0xff, 0xc5, 0x5e, 0x90, 0x01, 0x09, 0x02, 0x00, 0x00,
+	// 453 bytes of a gzipped FileDescriptorProto
+	0x1f,0x8b,0x08,0x00,0x00,0x00,0x00,0x00,0x02,0xff,0x74,0x52,0xcd,0x6e,0xd3,0x40,
+	0x18,0xf4,0x17,0x3b,0x24,0xfe,0x42,0x5a,0x6b,0x41,0xc2,0x32,0x68,0x15,0x59,0x1c,
+	0x2c,0xa4,0x26,0xc2,0x21,0x28,0x1c,0x4b,0xd3,0x43,0xdb,0x0b,0x72,0x11,0x1c,0x91,
+	0x1d,0x16,0xd7,0x6a,0x9c,0x0d,0xf6,0x1a,0x14,0x24,0xa4,0x3e,0x02,0x47,0x1e,0xa1,
+	0x2f,0xd0,0x17,0xe0,0xc4,0xb1,0x47,0x8e,0xd4,0x5c,0x38,0x22,0xf1,0x02,0xc8,0xbb,
+	0xcd,0x1f,0x88,0x93,0x67,0xc6,0xb3,0xdf,0xce,0xac,0x3e,0xc4,0x68,0xfe,0xe1,0x6d,
+	0x77,0x96,0x71,0xc1,0x89,0x51,0x61,0xe7,0x7e,0x9c,0x88,0x93,0x22,0xea,0x8e,0x79,
+	0xda,0xcb,0xd8,0x24,0x8c,0x7a,0x31,0xcf,0x8a,0x34,0xbf,0xfe,0x28,0xaf,0xb3,0xb3,
+	0xe6,0x8a,0x79,0xcc,0x7b,0x52,0x8e,0x8a,0x37,0x92,0x49,0x22,0x91,0xb2,0xbb,0x77,
+	0x50,0x3f,0x62,0x73,0x62,0xa1,0x7e,0xca,0xe6,0x36,0x74,0xc0,0x33,0x83,0x0a,0xba,
+	0xa7,0xd8,0xd8,0xe3,0x53,0xc1,0xa6,0xe2,0xdf,0x9f,0xe4,0x1e,0x9a,0x22,0x49,0x59,
+	0x2e,0xc2,0x74,0x66,0xd7,0x3a,0xe0,0xe9,0xc1,0x4a,0x20,0xb7,0xb1,0xfe,0x2e,0x9c,
+	0x14,0xcc,0xd6,0xe5,0x09,0x45,0x88,0x83,0xcd,0xf7,0x59,0x22,0x58,0x76,0x30,0xb2,
+	0x8d,0x0e,0x78,0xed,0x60,0xc9,0xdd,0x8f,0x58,0x7f,0x21,0x4d,0x77,0x11,0xc6,0xf2,
+	0xa2,0x96,0xdf,0xee,0xca,0x17,0xb8,0x0e,0x11,0xc0,0x98,0xec,0xa0,0x19,0x4e,0x62,
+	0x9e,0x25,0xe2,0x24,0x95,0x23,0xb6,0xfc,0x6d,0x65,0xda,0x5d,0xc8,0xc1,0xca,0x51,
+	0x85,0xcc,0x93,0x78,0x1a,0x8a,0x22,0x63,0x76,0xbd,0x03,0xde,0xcd,0x60,0x25,0x1c,
+	0x1a,0xcd,0x9a,0xa5,0x1f,0x1a,0x4d,0xdd,0x32,0xdc,0x03,0x6c,0xbf,0xac,0xa2,0x04,
+	0x2c,0x9f,0xf1,0x69,0xce,0x36,0xfb,0xc1,0xdf,0xfd,0xd6,0x9b,0xd4,0x36,0x9b,0x3c,
+	0x18,0xa2,0xb9,0x0c,0x43,0xb6,0x10,0xf7,0xf7,0x46,0xc7,0xbb,0xaf,0x9e,0xf9,0x83,
+	0xc7,0x96,0xb6,0xc6,0xfb,0xc3,0x47,0x16,0x90,0x16,0x36,0xf6,0x47,0xfe,0x60,0xf0,
+	0xf0,0x89,0x55,0xf3,0x3f,0x03,0x36,0x8e,0x05,0xcf,0xc2,0x98,0x11,0x8a,0x46,0xc0,
+	0xc2,0xd7,0xc4,0x54,0xf5,0x8e,0xd8,0xdc,0x69,0x29,0xa8,0x9e,0xa9,0x8f,0x75,0x19,
+	0x98,0xac,0xab,0xce,0x2d,0x45,0x36,0xaa,0xb8,0xcd,0xb3,0x0b,0x1b,0xce,0x2f,0x6c,
+	0x20,0x43,0x6c,0x57,0x43,0x9f,0x2f,0x7b,0xfc,0x67,0xba,0xbb,0x5d,0x1d,0xf9,0xf2,
+	0xdb,0x5e,0x2c,0xc0,0x53,0xef,0xf2,0x8a,0x6a,0xdf,0xae,0xa8,0x76,0x56,0x52,0x38,
+	0x2f,0x29,0x7c,0x2d,0x29,0x5c,0x96,0x14,0xbe,0x97,0x14,0x7e,0x96,0x54,0xfb,0x55,
+	0x52,0xf8,0xf4,0x83,0x6a,0xd1,0x0d,0xb9,0x55,0xfd,0x3f,0x03,0x00,0x3a,0x8f,0x04,
+	0xd9,0xbe,0x02,0x00,0x00,
 }
@@ -0,0 +1,253 @@
+package byzqtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Spec is a register specification.
+type Spec int
+
+const (
+	// Safe registers guarantee that a read that is not concurrent with any
+	// write returns the value of the last write preceding it.
+	Safe Spec = iota
+	// Regular registers additionally guarantee that a read that is
+	// concurrent with writes returns either the value of the last write
+	// preceding it, or the value of one of the concurrent writes.
+	Regular
+	// Atomic registers additionally guarantee that the operations appear to
+	// take effect in a total order consistent with real time.
+	Atomic
+)
+
+func (s Spec) String() string {
+	switch s {
+	case Safe:
+		return "safe"
+	case Regular:
+		return "regular"
+	case Atomic:
+		return "atomic"
+	}
+	return "unknown"
+}
+
+// Violation is returned by Check when a history does not satisfy a register
+// specification.
+type Violation struct {
+	Spec   Spec
+	Reason string
+	// Ops is a minimal sub-history that shows the violation, ordered by
+	// invocation.
+	Ops []Op
+}
+
+func (v *Violation) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "history is not %v: %s", v.Spec, v.Reason)
+	for _, op := range v.Ops {
+		fmt.Fprintf(&b, "\n\t%v", op)
+	}
+	return b.String()
+}
+
+// Check checks that the operations satisfy the register specification spec,
+// and returns a *Violation if they do not. Each key is checked as a separate
+// register whose initial value is the empty content. Written values are
+// identified by their timestamp and writer ID. A failed write with a zero
+// timestamp, as recorded by Recorder.WriteNext, takes the timestamp of the
+// first read that returned its value for its writer ID, and is ignored if
+// no read did.
+//
+// Atomicity is checked with the writes ordered by their timestamps and
+// writer IDs, which is the order in which byzq linearizes writes.
+func Check(ops []Op, spec Spec) error {
+	keys := make(map[string][]Op)
+	var order []string
+	for _, op := range ops {
+		k := op.Content.Key
+		if _, found := keys[k]; !found {
+			order = append(order, k)
+		}
+		keys[k] = append(keys[k], op)
+	}
+	for _, k := range order {
+		c := newChecker(keys[k], spec)
+		if v := c.check(); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+type version struct {
+	ts     int64
+	writer uint32
+}
+
+func versionOf(op Op) version {
+	return version{op.Content.Timestamp, op.Content.WriterID}
+}
+
+// checker checks the operations on a single key.
+type checker struct {
+	spec   Spec
+	reads  []Op
+	writes []Op
+	// written maps the versions to the writes, and readFrom maps the reads to
+	// the writes whose values they returned. Reads of the initial value are
+	// not in readFrom.
+	written  map[version]Op
+	readFrom map[int]Op
+}
+
+func newChecker(ops []Op, spec Spec) *checker {
+	c := &checker{
+		spec:     spec,
+		written:  make(map[version]Op),
+		readFrom: make(map[int]Op),
+	}
+	var unknown []Op // failed writes whose timestamps are unknown
+	for _, op := range ops {
+		switch {
+		case op.Kind != WriteOp:
+			c.reads = append(c.reads, op)
+		case op.Failed() && op.Content.Timestamp == 0:
+			unknown = append(unknown, op)
+		default:
+			c.addWrite(op)
+		}
+	}
+	for _, r := range c.reads {
+		if _, found := c.written[versionOf(r)]; found || initial(r) {
+			continue
+		}
+		for i, w := range unknown {
+			if w.Content.WriterID == r.Content.WriterID && w.Content.Value == r.Content.Value {
+				w.Content.Timestamp = r.Content.Timestamp
+				c.addWrite(w)
+				unknown = append(unknown[:i], unknown[i+1:]...)
+				break
+			}
+		}
+	}
+	return c
+}
+
+func (c *checker) addWrite(w Op) {
+	c.writes = append(c.writes, w)
+	if _, found := c.written[versionOf(w)]; !found {
+		c.written[versionOf(w)] = w
+	}
+}
+
+// precedes reports whether a returned before b was invoked.
+func precedes(a, b Op) bool {
+	return a.Return < b.Call
+}
+
+// newer reports whether the value of a is ordered after the value of b.
+func newer(a, b Op) bool {
+	return a.Content.Newer(&b.Content)
+}
+
+func initial(r Op) bool {
+	return versionOf(r) == version{}
+}
+
+func (c *checker) violation(reason string, ops ...Op) *Violation {
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Call < ops[j].Call })
+	return &Violation{Spec: c.spec, Reason: reason, Ops: ops}
+}
+
+// with returns ops and the write whose value was returned by read i, if any.
+func (c *checker) with(i int, ops ...Op) []Op {
+	if w, found := c.readFrom[i]; found {
+		return append(ops, w)
+	}
+	return ops
+}
+
+func (c *checker) check() *Violation {
+	for i, r := range c.reads {
+		if c.spec == Safe && c.concurrentWrite(r) {
+			// safe registers may return any value
+			continue
+		}
+		if v := c.checkRead(i, r); v != nil {
+			return v
+		}
+	}
+	if c.spec == Atomic {
+		return c.checkAtomic()
+	}
+	return nil
+}
+
+func (c *checker) concurrentWrite(r Op) bool {
+	for _, w := range c.writes {
+		if !precedes(w, r) && !precedes(r, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRead checks that read i returns the value of a write that was invoked
+// before the read returned, and that was not overwritten before the read
+// was invoked.
+func (c *checker) checkRead(i int, r Op) *Violation {
+	if initial(r) {
+		for _, w := range c.writes {
+			if precedes(w, r) {
+				return c.violation("read returned the initial value after a completed write", w, r)
+			}
+		}
+		return nil
+	}
+	w, found := c.written[versionOf(r)]
+	if !found || w.Content.Value != r.Content.Value {
+		return c.violation("read returned a value that was never written", r)
+	}
+	c.readFrom[i] = w
+	if precedes(r, w) {
+		return c.violation("read returned the value of a write invoked after the read returned", r, w)
+	}
+	for _, w2 := range c.writes {
+		if precedes(w, w2) && precedes(w2, r) {
+			return c.violation("read returned a value overwritten before the read was invoked", w, w2, r)
+		}
+	}
+	return nil
+}
+
+// checkAtomic checks that the order of the values is consistent with the
+// real-time order of the operations. Together with the regular checks, this
+// ensures that the history is linearizable.
+func (c *checker) checkAtomic() *Violation {
+	for _, w1 := range c.writes {
+		for _, w2 := range c.writes {
+			if precedes(w1, w2) && !newer(w2, w1) {
+				return c.violation("write is ordered before a write that precedes it", w1, w2)
+			}
+		}
+	}
+	for i, r := range c.reads {
+		for _, w := range c.writes {
+			if precedes(w, r) && newer(w, r) {
+				return c.violation("read returned a value older than a preceding write", c.with(i, w, r)...)
+			}
+			if precedes(r, w) && !newer(w, r) {
+				return c.violation("read returned a value not older than a following write", c.with(i, r, w)...)
+			}
+		}
+		for j, r2 := range c.reads {
+			if precedes(r, r2) && newer(r, r2) {
+				return c.violation("read returned a value older than a preceding read", c.with(j, c.with(i, r, r2)...)...)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package byzqtest
+
+import (
+	"testing"
+
+	"github.com/relab/byzq"
+)
+
+func w(value string, ts int64, call, ret int64) Op {
+	return Op{Kind: WriteOp, Content: byzq.Content{Key: "Winnie", Value: value, Timestamp: ts, WriterID: 1}, Call: call, Return: ret}
+}
+
+func r(value string, ts int64, call, ret int64) Op {
+	c := byzq.Content{Key: "Winnie"}
+	if ts > 0 {
+		c = byzq.Content{Key: "Winnie", Value: value, Timestamp: ts, WriterID: 1}
+	}
+	return Op{Kind: ReadOp, Content: c, Call: call, Return: ret}
+}
+
+var checkTests = []struct {
+	name string
+	ops  []Op
+	// weakest specification violated by the history, or -1 if none
+	violates  Spec
+	violation []Op
+}{
+	{
+		"empty history",
+		nil,
+		-1, nil,
+	},
+	{
+		"sequential",
+		[]Op{r("", 0, 1, 2), w("Poo", 1, 3, 4), r("Poo", 1, 5, 6), w("Tigger", 2, 7, 8), r("Tigger", 2, 9, 10)},
+		-1, nil,
+	},
+	{
+		"read concurrent with write",
+		[]Op{w("Poo", 1, 1, 2), w("Tigger", 2, 3, 6), r("Poo", 1, 4, 5), r("Tigger", 2, 7, 8)},
+		-1, nil,
+	},
+	{
+		"failed write",
+		[]Op{w("Poo", 1, 1, pending), r("", 0, 2, 3), r("Poo", 1, 4, 5)},
+		-1, nil,
+	},
+	{
+		"failed write with unknown timestamp",
+		[]Op{w("Poo", 1, 1, 2), w("Tigger", 0, 3, pending), r("Tigger", 2, 4, 5), w("Eeyore", 0, 6, pending), r("Tigger", 2, 7, 8)},
+		-1, nil,
+	},
+	{
+		"value never written",
+		[]Op{w("Poo", 1, 1, 2), r("Tigger", 1, 3, 4)},
+		Safe, []Op{r("Tigger", 1, 3, 4)},
+	},
+	{
+		"stale read",
+		[]Op{w("Poo", 1, 1, 2), w("Tigger", 2, 3, 4), r("Poo", 1, 5, 6)},
+		Safe, []Op{w("Poo", 1, 1, 2), w("Tigger", 2, 3, 4), r("Poo", 1, 5, 6)},
+	},
+	{
+		"initial value after write",
+		[]Op{r("", 0, 1, 2), w("Poo", 1, 3, 4), r("", 0, 5, 6)},
+		Safe, []Op{w("Poo", 1, 3, 4), r("", 0, 5, 6)},
+	},
+	{
+		"garbage read concurrent with write",
+		[]Op{w("Poo", 1, 1, 2), w("Tigger", 2, 3, 6), r("Heffalump", 7, 4, 5)},
+		Regular, []Op{r("Heffalump", 7, 4, 5)},
+	},
+	{
+		"stale read concurrent with write",
+		[]Op{w("Poo", 1, 1, 2), w("Tigger", 2, 3, 4), w("Eeyore", 3, 5, 8), r("Poo", 1, 6, 7)},
+		Regular, []Op{w("Poo", 1, 1, 2), w("Tigger", 2, 3, 4), r("Poo", 1, 6, 7)},
+	},
+	{
+		"value from the future",
+		[]Op{w("Poo", 1, 1, 2), w("Tigger", 2, 5, 8), r("Tigger", 2, 3, 4)},
+		Safe, []Op{r("Tigger", 2, 3, 4), w("Tigger", 2, 5, 8)},
+	},
+	{
+		"new-old inversion",
+		[]Op{w("Poo", 1, 1, 2), w("Tigger", 2, 3, 10), r("Tigger", 2, 4, 5), r("Poo", 1, 6, 7)},
+		Atomic, []Op{w("Poo", 1, 1, 2), w("Tigger", 2, 3, 10), r("Tigger", 2, 4, 5), r("Poo", 1, 6, 7)},
+	},
+	{
+		"writes ordered against real time",
+		[]Op{w("Tigger", 2, 1, 2), w("Poo", 1, 3, 4)},
+		Atomic, []Op{w("Tigger", 2, 1, 2), w("Poo", 1, 3, 4)},
+	},
+}
+
+func TestCheck(t *testing.T) {
+	for _, test := range checkTests {
+		for _, spec := range []Spec{Safe, Regular, Atomic} {
+			t.Run(test.name+"/"+spec.String(), func(t *testing.T) {
+				err := Check(test.ops, spec)
+				if test.violates < 0 || spec < test.violates {
+					if err != nil {
+						t.Fatalf("got error %v, want nil", err)
+					}
+					return
+				}
+				v, ok := err.(*Violation)
+				if !ok {
+					t.Fatalf("got error %v, want violation", err)
+				}
+				if v.Spec != spec {
+					t.Errorf("got violation of %v, want %v", v.Spec, spec)
+				}
+				if !equalOps(v.Ops, test.violation) {
+					t.Errorf("got violating sub-history %v, want %v", v.Ops, test.violation)
+				}
+			})
+		}
+	}
+}
+
+func equalOps(a, b []Op) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
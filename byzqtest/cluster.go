@@ -0,0 +1,237 @@
+// Package byzqtest provides an in-process cluster of byzq replicas for
+// protocol-level tests and benchmarks. The replicas communicate with clients
+// over in-memory gRPC connections, so no network ports are used.
+package byzqtest
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/relab/byzq"
+)
+
+// bufSize is the buffer size of the in-memory connections.
+const bufSize = 1 << 20
+
+// basePort is the port of the fake address of the first replica. The fake
+// addresses must be resolvable, since the Manager resolves node addresses
+// before dialing, but nothing listens on them.
+const basePort = 20000
+
+// Cluster is a set of in-process replicas and a Manager and Configuration
+// connected to them.
+type Cluster struct {
+	// Manager is connected to all replicas of the cluster.
+	Manager *byzq.Manager
+	// Config is a configuration of all replicas in the cluster.
+	Config *byzq.Configuration
+
+	mu        sync.Mutex
+	addrs     []string
+	replicas  []*replica
+	writers   *byzq.WriterRegistry
+	serverOps func(i int) []byzq.ServerOption
+}
+
+type replica struct {
+	server   *byzq.Server
+	listener *bufconn.Listener // nil if the replica is stopped
+}
+
+// NewCluster starts n replicas accepting writes from the given writers, and
+// returns a cluster with a configuration of the replicas using qspec.
+func NewCluster(n int, qspec byzq.QuorumSpec, writers *byzq.WriterRegistry, opts ...Option) (*Cluster, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c := &Cluster{
+		replicas:  make([]*replica, n),
+		writers:   writers,
+		serverOps: o.serverOpts,
+	}
+	for i := 0; i < n; i++ {
+		c.addrs = append(c.addrs, fmt.Sprintf("127.0.0.1:%d", basePort+i))
+		if err := c.start(i, c.serverOptions(i)); err != nil {
+			c.stopAll()
+			return nil, err
+		}
+	}
+
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithDialer(c.dial),
+		// calls to a stopped or restarted replica wait for the connection
+		// to be reestablished, instead of failing while the connection is
+		// backing off from the failed attempts to reach the stopped replica
+		grpc.WithDefaultCallOptions(grpc.WaitForReady(true)),
+	}, o.dialOpts...)
+	if o.network != nil {
+		o.network.attach(c.addrs)
+		dialOpts = append(dialOpts, grpc.WithUnaryInterceptor(o.network.intercept))
+		qspec = o.network.QuorumSpec(qspec)
+	}
+	mgrOpts := append(o.mgrOpts, byzq.WithGrpcDialOptions(dialOpts...))
+	mgr, err := byzq.NewManager(c.addrs, mgrOpts...)
+	if err != nil {
+		c.stopAll()
+		return nil, err
+	}
+	config, err := mgr.NewConfiguration(mgr.NodeIDs(), qspec)
+	if err != nil {
+		mgr.Close()
+		c.stopAll()
+		return nil, err
+	}
+	c.Manager, c.Config = mgr, config
+	return c, nil
+}
+
+// Size returns the number of replicas in the cluster.
+func (c *Cluster) Size() int {
+	return len(c.replicas)
+}
+
+// Addr returns the fake address of replica i, which identifies the replica
+// to the Manager.
+func (c *Cluster) Addr(i int) string {
+	return c.addrs[i]
+}
+
+// Stop stops replica i, closing its connections. Calls to a stopped replica
+// wait until it is restarted or the context of the call is done, like calls
+// to a crashed replica that never replies.
+func (c *Cluster) Stop(i int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stop(i)
+}
+
+// Restart stops replica i if it is running, and starts it again with the
+// same options as it was first started with. The state of the replica is
+// lost unless its options provide a store that survives restarts.
+func (c *Cluster) Restart(i int) error {
+	return c.Replace(i, c.serverOptions(i)...)
+}
+
+// Replace stops replica i if it is running, and starts a new replica with
+// the given server options at its address, e.g. to make it faulty.
+// The writer registry of the cluster is used unless opts provides another.
+func (c *Cluster) Replace(i int, opts ...byzq.ServerOption) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stop(i)
+	return c.start(i, opts)
+}
+
+// Close closes the Manager and stops all replicas.
+func (c *Cluster) Close() {
+	c.Manager.Close()
+	c.stopAll()
+}
+
+func (c *Cluster) serverOptions(i int) []byzq.ServerOption {
+	if c.serverOps == nil {
+		return nil
+	}
+	return c.serverOps(i)
+}
+
+// start starts replica i. The caller must hold c.mu or have exclusive
+// access to c.
+func (c *Cluster) start(i int, opts []byzq.ServerOption) error {
+	srv, err := byzq.NewServer(append([]byzq.ServerOption{byzq.WithWriters(c.writers)}, opts...)...)
+	if err != nil {
+		return err
+	}
+	l := bufconn.Listen(bufSize)
+	go srv.Serve(l)
+	c.replicas[i] = &replica{server: srv, listener: l}
+	return nil
+}
+
+// stop stops replica i if it is running. The caller must hold c.mu.
+func (c *Cluster) stop(i int) {
+	r := c.replicas[i]
+	if r == nil || r.listener == nil {
+		return
+	}
+	r.server.Stop()
+	r.listener.Close()
+	r.listener = nil
+}
+
+func (c *Cluster) stopAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.replicas {
+		c.stop(i)
+	}
+}
+
+// dial connects to the replica with the given fake address.
+func (c *Cluster) dial(addr string, timeout time.Duration) (net.Conn, error) {
+	c.mu.Lock()
+	var l *bufconn.Listener
+	for i, a := range c.addrs {
+		if a == addr && c.replicas[i] != nil {
+			l = c.replicas[i].listener
+		}
+	}
+	c.mu.Unlock()
+	if l == nil {
+		return nil, fmt.Errorf("dial %s: replica is stopped", addr)
+	}
+	return l.Dial()
+}
+
+type options struct {
+	serverOpts func(i int) []byzq.ServerOption
+	mgrOpts    []byzq.ManagerOption
+	dialOpts   []grpc.DialOption
+	network    *Network
+}
+
+// Option provides a way to set different options on a new Cluster.
+type Option func(*options)
+
+// WithServerOptions returns an Option which sets a function returning the
+// server options of replica i. The function is called again when replica i
+// is restarted.
+func WithServerOptions(opts func(i int) []byzq.ServerOption) Option {
+	return func(o *options) {
+		o.serverOpts = opts
+	}
+}
+
+// WithManagerOptions returns an Option which sets options of the Manager.
+// Dial options must be set with WithDialOptions, since the Manager's dial
+// options are set by the Cluster.
+func WithManagerOptions(opts ...byzq.ManagerOption) Option {
+	return func(o *options) {
+		o.mgrOpts = opts
+	}
+}
+
+// WithDialOptions returns an Option which sets additional gRPC dial options
+// used by the Manager to connect to the replicas.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOpts = opts
+	}
+}
+
+// WithNetwork returns an Option which connects the Manager to the replicas
+// through the simulated network. The quorum specification of the cluster's
+// configuration is wrapped by the network. A network can only be used by
+// one Cluster.
+func WithNetwork(network *Network) Option {
+	return func(o *options) {
+		o.network = network
+	}
+}
@@ -0,0 +1,143 @@
+package byzqtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/relab/byzq"
+)
+
+func newCluster(t testing.TB, n int, opts ...Option) (*Cluster, *byzq.AuthDataQ) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qspec, err := byzq.NewAuthDataQ(n, key, &key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := byzq.NewVerifier(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// writers 0 to 2 sign with the same key
+	c, err := NewCluster(n, qspec, byzq.NewWriterRegistry(verifier, verifier, verifier), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, qspec
+}
+
+func readValue(t *testing.T, c *Cluster, want string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	content, err := c.Config.ReadRegister(ctx, &byzq.Key{Key: "Winnie"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content.GetValue() != want {
+		t.Errorf("got %v, want value %q", content, want)
+	}
+}
+
+func writeValue(t *testing.T, c *Cluster, value string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.Config.WriteNext(ctx, 1, "Winnie", value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCluster(t *testing.T) {
+	c, _ := newCluster(t, 4)
+	defer c.Close()
+
+	writeValue(t, c, "Poo")
+	readValue(t, c, "Poo")
+
+	// one stopped replica is tolerated
+	c.Stop(3)
+	writeValue(t, c, "Tigger")
+	readValue(t, c, "Tigger")
+
+	// two stopped replicas prevent a quorum
+	c.Stop(2)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := c.Config.Read(ctx, &byzq.Key{Key: "Winnie"}); err == nil {
+		t.Error("got nil error from read with two stopped replicas")
+	}
+
+	// restarted replicas have lost their in-memory state, but a quorum
+	// still holds the latest value
+	if err := c.Restart(3); err != nil {
+		t.Fatal(err)
+	}
+	readValue(t, c, "Tigger")
+}
+
+func TestClusterRestartWithStore(t *testing.T) {
+	stores := make([]*byzq.MemStore, 4)
+	for i := range stores {
+		stores[i] = byzq.NewMemStore()
+	}
+	c, _ := newCluster(t, 4, WithServerOptions(func(i int) []byzq.ServerOption {
+		return []byzq.ServerOption{byzq.WithStore(stores[i])}
+	}))
+	defer c.Close()
+
+	writeValue(t, c, "Poo")
+	for i := 0; i < c.Size(); i++ {
+		if err := c.Restart(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readValue(t, c, "Poo")
+}
+
+func TestClusterReplace(t *testing.T) {
+	c, _ := newCluster(t, 4)
+	defer c.Close()
+
+	writeValue(t, c, "Poo")
+	if err := c.Replace(0, byzq.WithFaults(byzq.DropWrites)); err != nil {
+		t.Fatal(err)
+	}
+	writeValue(t, c, "Tigger")
+	readValue(t, c, "Tigger")
+}
+
+func BenchmarkCluster(b *testing.B) {
+	for _, n := range []int{4, 7, 10} {
+		c, _ := newCluster(b, n)
+		ctx := context.Background()
+		if _, err := c.Config.WriteNext(ctx, 1, "Winnie", "Poo"); err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("Read(%d)", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Config.Read(ctx, &byzq.Key{Key: "Winnie"}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("WriteNext(%d)", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Config.WriteNext(ctx, 1, "Winnie", "Poo"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		c.Close()
+	}
+}
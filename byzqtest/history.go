@@ -0,0 +1,193 @@
+package byzqtest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/relab/byzq"
+)
+
+// OpKind is the kind of a register operation.
+type OpKind int
+
+const (
+	// ReadOp is a read of a key.
+	ReadOp OpKind = iota
+	// WriteOp is a write of a key.
+	WriteOp
+)
+
+func (k OpKind) String() string {
+	switch k {
+	case ReadOp:
+		return "read"
+	case WriteOp:
+		return "write"
+	}
+	return "unknown"
+}
+
+// pending is the return time of writes that failed. A failed write may or
+// may not have taken effect, so it is treated as never returning.
+const pending = math.MaxInt64
+
+// Op is a completed operation in a history. Call and Return are logical
+// times of the invocation and response of the operation; an operation a
+// precedes operation b in real time if a.Return < b.Call.
+type Op struct {
+	Kind   OpKind
+	Client int
+	// Content is the content written, or the content returned by a read.
+	// A read of a key that has not been written returns empty content.
+	Content byzq.Content
+	Call    int64
+	Return  int64
+}
+
+// Failed reports whether the operation is a write that returned an error.
+func (op Op) Failed() bool {
+	return op.Return == pending
+}
+
+func (op Op) String() string {
+	ret := fmt.Sprint(op.Return)
+	if op.Failed() {
+		ret = "failed"
+	}
+	return fmt.Sprintf("client %d: %v(%s)=%q ts=%d writer=%d [%d, %s]",
+		op.Client, op.Kind, op.Content.Key, op.Content.Value,
+		op.Content.Timestamp, op.Content.WriterID, op.Call, ret)
+}
+
+// History is a concurrent history of register operations.
+// It is safe for concurrent use.
+type History struct {
+	mu    sync.Mutex
+	clock int64
+	ops   []Op
+}
+
+// NewHistory returns an empty history.
+func NewHistory() *History {
+	return &History{}
+}
+
+// now returns the next logical time.
+func (h *History) now() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clock++
+	return h.clock
+}
+
+// add adds a completed operation to the history, and sets its return time
+// unless the operation has failed.
+func (h *History) add(op Op) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clock++
+	if op.Return == 0 {
+		op.Return = h.clock
+	}
+	h.ops = append(h.ops, op)
+}
+
+// Ops returns the operations of the history ordered by their invocation.
+func (h *History) Ops() []Op {
+	h.mu.Lock()
+	ops := append([]Op(nil), h.ops...)
+	h.mu.Unlock()
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Call < ops[j].Call })
+	return ops
+}
+
+// Check checks that the history satisfies the register specification spec,
+// and returns a *Violation if it does not.
+func (h *History) Check(spec Spec) error {
+	return Check(h.Ops(), spec)
+}
+
+// Recorder records the operations of a client on a configuration in a
+// history.
+type Recorder struct {
+	config  *byzq.Configuration
+	history *History
+	client  int
+}
+
+// NewRecorder returns a recorder for the given client that invokes
+// operations on config and records them in h.
+func NewRecorder(h *History, client int, config *byzq.Configuration) *Recorder {
+	return &Recorder{config: config, history: h, client: client}
+}
+
+// Read invokes Read on the configuration and records the read if it
+// succeeds. Failed reads are not recorded, since they return no value.
+// A read of a key that has never been written fails with a NoValueError
+// without invalid replies; it is recorded and returned as a read of the
+// initial value, i.e. with nil content and no error.
+func (r *Recorder) Read(ctx context.Context, k *byzq.Key) (*byzq.Content, error) {
+	return r.read(k, func() (*byzq.Content, error) { return r.config.Read(ctx, k) })
+}
+
+// ReadRegister invokes ReadRegister on the configuration and records the
+// read if it succeeds.
+func (r *Recorder) ReadRegister(ctx context.Context, k *byzq.Key) (*byzq.Content, error) {
+	return r.read(k, func() (*byzq.Content, error) { return r.config.ReadRegister(ctx, k) })
+}
+
+func (r *Recorder) read(k *byzq.Key, read func() (*byzq.Content, error)) (*byzq.Content, error) {
+	call := r.history.now()
+	c, err := read()
+	if e, ok := err.(*byzq.NoValueError); ok && e.Invalid == 0 {
+		c, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	op := Op{Kind: ReadOp, Client: r.client, Content: byzq.Content{Key: k.Key}, Call: call}
+	if c != nil {
+		op.Content = *c
+		op.Content.Key = k.Key
+	}
+	r.history.add(op)
+	return c, nil
+}
+
+// Write invokes Write on the configuration and records the write.
+// A failed write is recorded as never returning, since it may have
+// taken effect at some replicas.
+func (r *Recorder) Write(ctx context.Context, v *byzq.Value) (*byzq.WriteResponse, error) {
+	return r.write(ctx, r.history.now(), v)
+}
+
+func (r *Recorder) write(ctx context.Context, call int64, v *byzq.Value) (*byzq.WriteResponse, error) {
+	wr, err := r.config.Write(ctx, v)
+	op := Op{Kind: WriteOp, Client: r.client, Content: *v.C, Call: call}
+	if err != nil {
+		op.Return = pending
+	}
+	r.history.add(op)
+	return wr, err
+}
+
+// WriteNext invokes WriteNext on the configuration and records the write
+// with the acknowledged timestamp. A failed write is recorded as never
+// returning with a zero timestamp, since the timestamp chosen by WriteNext is
+// unknown; Check takes its timestamp from the reads that returned its value.
+func (r *Recorder) WriteNext(ctx context.Context, writerID uint32, key, value string) (*byzq.WriteResponse, error) {
+	call := r.history.now()
+	wr, err := r.config.WriteNext(ctx, writerID, key, value)
+	op := Op{Kind: WriteOp, Client: r.client, Content: byzq.Content{Key: key, Value: value, WriterID: writerID}, Call: call}
+	if err != nil {
+		op.Return = pending
+	} else {
+		op.Content.Timestamp = wr.Timestamp
+	}
+	r.history.add(op)
+	return wr, err
+}
@@ -0,0 +1,64 @@
+package byzqtest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/relab/byzq"
+)
+
+func TestHistory(t *testing.T) {
+	for _, test := range []struct {
+		semantics byzq.Semantics
+		spec      Spec
+	}{
+		{byzq.Regular, Regular},
+		{byzq.Atomic, Atomic},
+	} {
+		t.Run(test.semantics.String(), func(t *testing.T) {
+			c, qspec := newCluster(t, 4)
+			defer c.Close()
+			qspec.SetSemantics(test.semantics)
+
+			h := NewHistory()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			var wg sync.WaitGroup
+			errs := make(chan error, 5)
+			for client := 0; client < 5; client++ {
+				wg.Add(1)
+				go func(client int) {
+					defer wg.Done()
+					rec := NewRecorder(h, client, c.Config)
+					for i := 0; i < 10; i++ {
+						var err error
+						if client < 2 {
+							_, err = rec.WriteNext(ctx, uint32(client+1), "Winnie", fmt.Sprintf("%d-%d", client, i))
+						} else {
+							_, err = rec.ReadRegister(ctx, &byzq.Key{Key: "Winnie"})
+						}
+						if err != nil {
+							errs <- err
+							return
+						}
+					}
+				}(client)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Fatal(err)
+			}
+			if got := len(h.Ops()); got != 50 {
+				t.Errorf("got %d operations in history, want 50", got)
+			}
+			if err := h.Check(test.spec); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
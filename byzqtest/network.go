@@ -0,0 +1,467 @@
+package byzqtest
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/relab/byzq"
+)
+
+// NetworkConfig configures the faults of a simulated network.
+type NetworkConfig struct {
+	// MinDelay and MaxDelay bound the simulated delay of each message.
+	// Delays are drawn uniformly from [MinDelay, MaxDelay] and determine the
+	// order in which the replies of a quorum call are passed to the quorum
+	// function; they do not slow down the test.
+	MinDelay, MaxDelay time.Duration
+	// DropRate is the probability that a message is dropped.
+	DropRate float64
+	// PartitionRate is the probability that the link to a replica is
+	// partitioned at a quorum call, if it is not already partitioned.
+	PartitionRate float64
+	// MaxPartition is the maximum number of quorum calls a random partition
+	// lasts. The default is one.
+	MaxPartition int
+}
+
+// Network is a simulated network between the client and the replicas of a
+// Cluster. All scheduling decisions are derived from a seed, so that any run
+// can be replayed exactly by using the same seed: the replies of each quorum
+// call are passed to the quorum function in the order given by their
+// simulated delays, regardless of the order in which they actually arrive,
+// and dropped messages and partitions are chosen by the seed.
+//
+// The schedule is deterministic when quorum calls are invoked one at a time
+// on a configuration of all replicas, each with a distinct argument.
+// Messages on a link are delivered to the replica in the order the quorum
+// calls were invoked. The state of a quorum call is dropped once all its
+// messages have been delivered or dropped, and its quorum function is done.
+// ReadRegister uses the register semantics of the wrapped quorum
+// specification. Read-repair writes are sent to single replicas outside of
+// quorum calls, which cannot be scheduled, so read-repair must not be
+// enabled.
+type Network struct {
+	seed int64
+	cfg  NetworkConfig
+
+	mu         sync.Mutex
+	cond       *sync.Cond
+	replicas   map[string]int // replica index by address
+	n          int
+	nextCall   int64
+	callOf     map[interface{}]int64 // latest call of each request argument
+	msgs       map[msgID]*message
+	sentBy     map[interface{}]msgID // message of each reply
+	delivered  []int64               // next call to be delivered at each replica
+	partitions [][]partition         // partitions of each link
+	evaluated  []int64               // next call to evaluate for random partitions
+	calls      map[int64]*callState  // quorum calls that have not been pruned
+}
+
+type msgID struct {
+	replica int
+	call    int64
+}
+
+type message struct {
+	reply     interface{}
+	dropped   bool
+	completed bool
+	failed    bool
+}
+
+// partition drops the messages of calls in [from, to).
+type partition struct {
+	from, to int64
+}
+
+// callState holds the replies of a quorum call passed to the quorum function.
+type callState struct {
+	call      int64
+	req       interface{}
+	order     []int // replicas in the order of their simulated delays
+	pos       int   // next position in order
+	completed int   // messages delivered or dropped
+	done      bool  // the quorum function will not be called again
+	values    []*byzq.Value
+	results   []*byzq.WriteResponse
+}
+
+// NewNetwork returns a simulated network whose schedule is given by seed.
+func NewNetwork(seed int64, cfg NetworkConfig) *Network {
+	if cfg.MaxPartition < 1 {
+		cfg.MaxPartition = 1
+	}
+	n := &Network{
+		seed:   seed,
+		cfg:    cfg,
+		callOf: make(map[interface{}]int64),
+		msgs:   make(map[msgID]*message),
+		sentBy: make(map[interface{}]msgID),
+		calls:  make(map[int64]*callState),
+	}
+	n.cond = sync.NewCond(&n.mu)
+	return n
+}
+
+// Seed returns the seed of the network.
+func (n *Network) Seed() int64 {
+	return n.seed
+}
+
+// attach connects the network to the replicas with the given addresses.
+func (n *Network) attach(addrs []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.n = len(addrs)
+	n.replicas = make(map[string]int)
+	for i, addr := range addrs {
+		n.replicas[addr] = i
+	}
+	n.delivered = make([]int64, n.n)
+	n.partitions = make([][]partition, n.n)
+	n.evaluated = make([]int64, n.n)
+}
+
+// Partition drops all messages to replica i from the next quorum call
+// until Heal is called.
+func (n *Network) Partition(i int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.partitions[i] = append(n.partitions[i], partition{n.nextCall, 1<<63 - 1})
+}
+
+// Heal ends the partitions of replica i started by Partition from the next
+// quorum call.
+func (n *Network) Heal(i int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for j, p := range n.partitions[i] {
+		if p.to > n.nextCall {
+			n.partitions[i][j].to = n.nextCall
+		}
+	}
+}
+
+// QuorumSpec returns a quorum specification that passes the replies of each
+// quorum call to qspec in the order of their simulated delays.
+func (n *Network) QuorumSpec(qspec byzq.QuorumSpec) byzq.QuorumSpec {
+	q := &simQSpec{qspec: qspec, net: n}
+	if eq, ok := qspec.(byzq.EvaluatorQuorumSpec); ok {
+		return &simEvaluatorQSpec{simQSpec: q, eval: eq}
+	}
+	return q
+}
+
+// intercept is a gRPC client interceptor that schedules the messages of
+// quorum calls.
+func (n *Network) intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	n.mu.Lock()
+	i, found := n.replicas[cc.Target()]
+	if !found {
+		n.mu.Unlock()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	id := msgID{i, n.callFor(i, req)}
+	m := &message{reply: reply, dropped: n.dropped(id)}
+	n.msgs[id] = m
+	n.sentBy[reply] = id
+	for n.delivered[i] != id.call {
+		// deliver messages on a link in the order of the calls
+		n.cond.Wait()
+	}
+	n.mu.Unlock()
+
+	var err error
+	if m.dropped {
+		err = status.Errorf(codes.Unavailable, "simulated network dropped message to replica %d", i)
+	} else {
+		err = invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	n.mu.Lock()
+	m.completed, m.failed = true, err != nil
+	n.delivered[i]++
+	n.prunePartitions(i)
+	cs := n.calls[id.call]
+	cs.completed++
+	if ctx.Err() != nil {
+		// the quorum call has returned or is about to
+		cs.done = true
+	}
+	n.prune(cs)
+	n.cond.Broadcast()
+	n.mu.Unlock()
+	return err
+}
+
+// prune drops the state of the quorum call cs once all its messages have been
+// delivered or dropped, and its quorum function is done.
+func (n *Network) prune(cs *callState) {
+	if _, found := n.calls[cs.call]; !found || !cs.done || cs.completed < n.n {
+		return
+	}
+	for i := 0; i < n.n; i++ {
+		id := msgID{i, cs.call}
+		delete(n.sentBy, n.msgs[id].reply)
+		delete(n.msgs, id)
+	}
+	if n.callOf[cs.req] == cs.call {
+		delete(n.callOf, cs.req)
+	}
+	delete(n.calls, cs.call)
+}
+
+// finish marks the quorum function of cs as done.
+func (n *Network) finish(cs *callState) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	cs.done = true
+	n.prune(cs)
+}
+
+// prunePartitions drops the partitions of the link to replica i that end
+// before the next call to be delivered to it.
+func (n *Network) prunePartitions(i int) {
+	ps := n.partitions[i][:0]
+	for _, p := range n.partitions[i] {
+		if p.to > n.delivered[i] {
+			ps = append(ps, p)
+		}
+	}
+	n.partitions[i] = ps
+}
+
+// callFor returns the quorum call of a message with argument req to replica i.
+// The messages of a quorum call share the argument, which identifies the
+// call even if a message is sent after a later call has started.
+func (n *Network) callFor(i int, req interface{}) int64 {
+	if call, found := n.callOf[req]; found {
+		if _, sent := n.msgs[msgID{i, call}]; !sent {
+			return call
+		}
+	}
+	call := n.nextCall
+	n.nextCall++
+	n.callOf[req] = call
+	cs := &callState{call: call, req: req}
+	for i := 0; i < n.n; i++ {
+		cs.order = append(cs.order, i)
+	}
+	sort.SliceStable(cs.order, func(a, b int) bool {
+		return n.delay(cs.order[a], call) < n.delay(cs.order[b], call)
+	})
+	n.calls[call] = cs
+	return call
+}
+
+// dropped reports whether message id is dropped by the network.
+func (n *Network) dropped(id msgID) bool {
+	for ; n.evaluated[id.replica] <= id.call; n.evaluated[id.replica]++ {
+		call := n.evaluated[id.replica]
+		if n.partitioned(id.replica, call) {
+			continue
+		}
+		if n.rand(id.replica, call, saltPartition) < n.cfg.PartitionRate {
+			length := 1 + int64(n.rand(id.replica, call, saltPartitionLength)*float64(n.cfg.MaxPartition))
+			n.partitions[id.replica] = append(n.partitions[id.replica], partition{call, call + length})
+		}
+	}
+	return n.partitioned(id.replica, id.call) || n.rand(id.replica, id.call, saltDrop) < n.cfg.DropRate
+}
+
+func (n *Network) partitioned(i int, call int64) bool {
+	for _, p := range n.partitions[i] {
+		if p.from <= call && call < p.to {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *Network) delay(i int, call int64) time.Duration {
+	d := float64(n.cfg.MaxDelay - n.cfg.MinDelay)
+	return n.cfg.MinDelay + time.Duration(n.rand(i, call, saltDelay)*d)
+}
+
+const (
+	saltDrop uint64 = iota + 1
+	saltDelay
+	saltPartition
+	saltPartitionLength
+)
+
+// rand returns a pseudo-random number in [0, 1) derived from the seed, the
+// replica, the call and the salt. Deriving each number from its inputs,
+// rather than drawing from a stream, makes the numbers independent of the
+// order in which messages are sent.
+func (n *Network) rand(i int, call int64, salt uint64) float64 {
+	x := mix(uint64(n.seed) ^ salt*0x9e3779b97f4a7c15)
+	x = mix(x ^ uint64(i))
+	x = mix(x ^ uint64(call))
+	return float64(x>>11) / (1 << 53)
+}
+
+// mix is the finalizer of the SplitMix64 generator.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// next returns the call state of the quorum call whose replies have been
+// received, and the replies to pass to the quorum function next, in the
+// order of their simulated delays. Replies that have not been received are
+// waited for by the quorum call, so next stops at the first such reply.
+// If the quorum call has been pruned after its context was done, next
+// returns a nil call state.
+func (n *Network) next(received []interface{}) (*callState, []interface{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	id, found := n.sentBy[received[0]]
+	if !found {
+		return nil, nil
+	}
+	cs := n.calls[id.call]
+	present := make(map[interface{}]bool, len(received))
+	for _, r := range received {
+		present[r] = true
+	}
+	var next []interface{}
+	for ; cs.pos < len(cs.order); cs.pos++ {
+		m := n.msgs[msgID{cs.order[cs.pos], id.call}]
+		for m == nil || !m.completed {
+			// the outcome of the message decides whether it is passed on
+			n.cond.Wait()
+			m = n.msgs[msgID{cs.order[cs.pos], id.call}]
+		}
+		if m.failed {
+			continue
+		}
+		if !present[m.reply] {
+			break
+		}
+		next = append(next, m.reply)
+	}
+	if cs.pos == len(cs.order) {
+		// all replies have been received
+		cs.done = true
+		n.prune(cs)
+	}
+	return cs, next
+}
+
+// simQSpec passes the replies of quorum calls to the wrapped quorum
+// specification in the order given by a simulated network.
+type simQSpec struct {
+	qspec byzq.QuorumSpec
+	net   *Network
+}
+
+// Unwrap returns the wrapped quorum specification, so that its settings
+// apply to configurations using the simulated network.
+func (q *simQSpec) Unwrap() byzq.QuorumSpec {
+	return q.qspec
+}
+
+// Sign signs content with the wrapped quorum specification, so that
+// WriteNext can be used with the simulated network.
+func (q *simQSpec) Sign(content *byzq.Content) (*byzq.Value, error) {
+	s, ok := q.qspec.(interface {
+		Sign(*byzq.Content) (*byzq.Value, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("quorum specification %T cannot sign values", q.qspec)
+	}
+	return s.Sign(content)
+}
+
+func (q *simQSpec) ReadQF(replies []*byzq.Value) (*byzq.Content, bool, error) {
+	rq, ok := q.qspec.(byzq.ReadQuorumSpec)
+	if !ok {
+		return nil, false, fmt.Errorf("quorum specification %T has no read quorum function", q.qspec)
+	}
+	return q.readQF(replies, rq.ReadQF)
+}
+
+func (q *simQSpec) ReadTimestampQF(replies []*byzq.Value) (*byzq.Content, bool) {
+	c, quorum, _ := q.readQF(replies, func(replies []*byzq.Value) (*byzq.Content, bool, error) {
+		c, quorum := q.qspec.ReadTimestampQF(replies)
+		return c, quorum, nil
+	})
+	return c, quorum
+}
+
+func (q *simQSpec) readQF(replies []*byzq.Value, qf func([]*byzq.Value) (*byzq.Content, bool, error)) (*byzq.Content, bool, error) {
+	received := make([]interface{}, len(replies))
+	for i, r := range replies {
+		received[i] = r
+	}
+	cs, next := q.net.next(received)
+	for _, r := range next {
+		cs.values = append(cs.values, r.(*byzq.Value))
+		if c, quorum, err := qf(cs.values); quorum || err != nil {
+			q.net.finish(cs)
+			return c, quorum, err
+		}
+	}
+	return nil, false, nil
+}
+
+func (q *simQSpec) WriteQF(req *byzq.Value, replies []*byzq.WriteResponse) (*byzq.WriteResponse, bool) {
+	received := make([]interface{}, len(replies))
+	for i, r := range replies {
+		received[i] = r
+	}
+	cs, next := q.net.next(received)
+	for _, r := range next {
+		cs.results = append(cs.results, r.(*byzq.WriteResponse))
+		if wr, quorum := q.qspec.WriteQF(req, cs.results); quorum {
+			q.net.finish(cs)
+			return wr, true
+		}
+	}
+	return nil, false
+}
+
+// simEvaluatorQSpec passes the replies of Read quorum calls to evaluators of
+// the wrapped quorum specification in the order given by a simulated network.
+type simEvaluatorQSpec struct {
+	*simQSpec
+	eval byzq.EvaluatorQuorumSpec
+}
+
+func (q *simEvaluatorQSpec) NewReadEvaluator() byzq.ReadEvaluator {
+	return &simEvaluator{net: q.net, eval: q.eval.NewReadEvaluator()}
+}
+
+// simEvaluator passes the replies of a Read quorum call to the wrapped
+// evaluator once they are next in the order given by the simulated network.
+type simEvaluator struct {
+	net      *Network
+	eval     byzq.ReadEvaluator
+	received []interface{}
+	result   byzq.ReadResult
+}
+
+func (e *simEvaluator) Add(reply *byzq.Value) byzq.ReadResult {
+	e.received = append(e.received, reply)
+	cs, next := e.net.next(e.received)
+	for _, r := range next {
+		if e.result = e.eval.Add(r.(*byzq.Value)); e.result.Quorum {
+			e.net.finish(cs)
+			break
+		}
+	}
+	return e.result
+}
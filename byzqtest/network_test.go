@@ -0,0 +1,207 @@
+package byzqtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/relab/byzq"
+)
+
+// loggingQSpec logs the replies passed to its read evaluators.
+type loggingQSpec struct {
+	*byzq.AuthDataQ
+	mu  sync.Mutex
+	log []string
+}
+
+func (q *loggingQSpec) NewReadEvaluator() byzq.ReadEvaluator {
+	return &loggingEvaluator{ReadEvaluator: q.AuthDataQ.NewReadEvaluator(), qspec: q}
+}
+
+// loggingEvaluator logs the replies added so far on every reply.
+type loggingEvaluator struct {
+	byzq.ReadEvaluator
+	qspec *loggingQSpec
+	b     strings.Builder
+}
+
+func (e *loggingEvaluator) Add(reply *byzq.Value) byzq.ReadResult {
+	fmt.Fprintf(&e.b, "%d,", reply.GetC().GetTimestamp())
+	e.qspec.mu.Lock()
+	e.qspec.log = append(e.qspec.log, e.b.String())
+	e.qspec.mu.Unlock()
+	return e.ReadEvaluator.Add(reply)
+}
+
+// runNetwork runs a sequence of writes and reads over a simulated network,
+// and returns the outcomes of the operations and the replies passed to the
+// read evaluators.
+func runNetwork(t *testing.T, seed int64) []string {
+	network := NewNetwork(seed, NetworkConfig{
+		MinDelay:      time.Millisecond,
+		MaxDelay:      10 * time.Millisecond,
+		DropRate:      0.1,
+		PartitionRate: 0.05,
+		MaxPartition:  3,
+	})
+	c, qspec := newCluster(t, 4, WithNetwork(network))
+	defer c.Close()
+	logging := &loggingQSpec{AuthDataQ: qspec}
+	config, err := c.Manager.NewConfiguration(c.Manager.NodeIDs(), network.QuorumSpec(logging))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var outcomes []string
+	for i := 0; i < 20; i++ {
+		if i%3 == 0 {
+			_, err := config.WriteNext(ctx, 1, "Winnie", fmt.Sprint(i))
+			outcomes = append(outcomes, fmt.Sprintf("write %d: %v", i, err))
+			continue
+		}
+		content, err := config.Read(ctx, &byzq.Key{Key: "Winnie"})
+		outcomes = append(outcomes, fmt.Sprintf("read: %v %v", content, err))
+	}
+	return append(outcomes, logging.log...)
+}
+
+func TestNetworkReplay(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		first := runNetwork(t, seed)
+		for run := 0; run < 3; run++ {
+			if again := runNetwork(t, seed); strings.Join(again, "\n") != strings.Join(first, "\n") {
+				t.Fatalf("seed %d: replay differs:\n%s\nwant:\n%s", seed, strings.Join(again, "\n"), strings.Join(first, "\n"))
+			}
+		}
+	}
+}
+
+func TestNetworkPartition(t *testing.T) {
+	network := NewNetwork(1, NetworkConfig{MaxDelay: time.Millisecond})
+	c, _ := newCluster(t, 4, WithNetwork(network))
+	defer c.Close()
+
+	writeValue(t, c, "Poo")
+	network.Partition(0)
+	writeValue(t, c, "Tigger")
+	readValue(t, c, "Tigger")
+
+	network.Partition(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := c.Config.Read(ctx, &byzq.Key{Key: "Winnie"}); err == nil {
+		t.Error("got nil error from read with two partitioned replicas")
+	}
+
+	network.Heal(0)
+	network.Heal(1)
+	readValue(t, c, "Tigger")
+}
+
+func TestNetworkAtomic(t *testing.T) {
+	network := NewNetwork(1, NetworkConfig{MaxDelay: time.Millisecond})
+	c, qspec := newCluster(t, 4, WithNetwork(network))
+	defer c.Close()
+	qspec.SetSemantics(byzq.Atomic)
+
+	for _, value := range []string{"Poo", "Tigger", "Eeyore"} {
+		writeValue(t, c, value)
+		network.mu.Lock()
+		before := network.nextCall
+		network.mu.Unlock()
+		readValue(t, c, value)
+		network.mu.Lock()
+		calls := network.nextCall - before
+		network.mu.Unlock()
+		if calls != 2 {
+			t.Errorf("got %d quorum calls for an atomic read, want a read and a write-back", calls)
+		}
+	}
+
+	// the messages of the last quorum call may complete after it returns
+	var pending int
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		network.mu.Lock()
+		pending = len(network.calls) + len(network.msgs) + len(network.sentBy) + len(network.callOf)
+		network.mu.Unlock()
+		if pending == 0 {
+			break
+		}
+	}
+	if pending != 0 {
+		t.Errorf("got %d entries for completed quorum calls, want none", pending)
+	}
+}
+
+// countingVerifier counts the verifications passed to a Verifier.
+type countingVerifier struct {
+	byzq.Verifier
+	n uint64
+}
+
+func (v *countingVerifier) Verify(msg, sig []byte) bool {
+	atomic.AddUint64(&v.n, 1)
+	return v.Verifier.Verify(msg, sig)
+}
+
+func TestNetworkReadEvaluator(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := byzq.NewSigner(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := byzq.NewVerifier(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter := &countingVerifier{Verifier: verifier}
+	qspec, err := byzq.NewAuthDataQFromScheme(4, signer, counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	network := NewNetwork(1, NetworkConfig{MaxDelay: time.Millisecond})
+	if _, ok := network.QuorumSpec(qspec).(byzq.EvaluatorQuorumSpec); !ok {
+		t.Fatal("got quorum specification without read evaluators")
+	}
+	faulty := func(i int) []byzq.ServerOption {
+		if i == 0 {
+			return []byzq.ServerOption{byzq.WithFaults(byzq.BitFlip)}
+		}
+		return nil
+	}
+	c, err := NewCluster(4, qspec, byzq.NewWriterRegistry(verifier, verifier, verifier), WithNetwork(network), WithServerOptions(faulty))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	writeValue(t, c, "Poo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 10; i++ {
+		before := atomic.LoadUint64(&counter.n)
+		content, err := c.Config.Read(ctx, &byzq.Key{Key: "Winnie"})
+		if err != nil || content.GetValue() != "Poo" {
+			t.Fatalf("got %v, %v, want value %q", content, err, "Poo")
+		}
+		// each of the at most 4 replies is verified once, also when the
+		// invalid reply of the faulty replica delays the quorum
+		if n := atomic.LoadUint64(&counter.n) - before; n > 4 {
+			t.Errorf("read %d: got %d verifications, want at most 4", i, n)
+		}
+	}
+}
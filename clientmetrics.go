@@ -0,0 +1,350 @@
+package byzq
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Quorum call outcomes.
+const (
+	OutcomeQuorum     = "quorum"     // the quorum function found a quorum
+	OutcomeNoValue    = "novalue"    // a quorum was found, but no valid value
+	OutcomeIncomplete = "incomplete" // all nodes replied without a quorum
+	OutcomeTimeout    = "timeout"    // the context was done before a quorum
+	OutcomeFailed     = "failed"     // the quorum function returned an error
+)
+
+// NodeStats are the statistics of the calls to a node.
+type NodeStats struct {
+	Latency           Histogram
+	Errors            uint64
+	InvalidSignatures uint64
+}
+
+// CallStats are the statistics of a quorum call method.
+type CallStats struct {
+	// Outcomes counts the quorum calls by outcome.
+	Outcomes map[string]uint64
+	// RepliesNeeded counts the quorum calls that found a quorum by the
+	// number of replies passed to the quorum function.
+	RepliesNeeded map[int]uint64
+}
+
+// ClientMetricsSnapshot is a snapshot of client metrics.
+type ClientMetricsSnapshot struct {
+	// Nodes holds the statistics of each node by address.
+	Nodes map[string]NodeStats
+	// Calls holds the statistics of each quorum call method by name,
+	// e.g. "Read".
+	Calls map[string]CallStats
+}
+
+// ClientMetrics collects metrics of the quorum calls of a client on a
+// configuration. Calls to nodes are observed by a gRPC interceptor installed
+// with DialOption. The outcomes of the quorum calls issued by Read,
+// ReadRegister and WriteNext are recorded when the quorum calls return, if
+// the configuration's quorum specification is wrapped with QuorumSpec; the
+// generated Write and ReadTimestamp quorum calls are not observed.
+type ClientMetrics struct {
+	verifier Verifier
+	writers  *WriterRegistry // verifiers by writer ID (nil if verifier is used)
+
+	mu    sync.Mutex
+	nodes map[string]*nodeStats
+	calls map[string]*callStats
+}
+
+type nodeStats struct {
+	latency *histogram
+	errors  uint64
+	invalid uint64
+}
+
+type callStats struct {
+	outcomes map[string]uint64
+	replies  map[int]uint64
+}
+
+// NewClientMetrics returns client metrics. If verifier is non-nil, each reply
+// carrying a value is verified when it is received to count invalid
+// signatures by node. This doubles the cost of verification.
+func NewClientMetrics(verifier Verifier) *ClientMetrics {
+	return &ClientMetrics{
+		verifier: verifier,
+		nodes:    make(map[string]*nodeStats),
+		calls:    make(map[string]*callStats),
+	}
+}
+
+// SetWriters makes m verify each value with the verifier of the writer whose
+// ID the value holds in writers instead of with the verifier m was created
+// with, as AuthDataQ.SetWriters does. It must be called before m is used.
+func (m *ClientMetrics) SetWriters(writers *WriterRegistry) {
+	m.writers = writers
+}
+
+// DialOption returns a gRPC dial option that observes the calls to each
+// node. It must be passed to the Manager with WithGrpcDialOptions.
+func (m *ClientMetrics) DialOption() grpc.DialOption {
+	return grpc.WithUnaryInterceptor(m.intercept)
+}
+
+// QuorumSpec returns a quorum specification that uses the quorum functions
+// of qspec, and makes the configurations using it record the outcomes of
+// their quorum calls in m. If qspec is a NodeQuorumSpec, so is the returned
+// quorum specification; otherwise, if qspec is an EvaluatorQuorumSpec, so is
+// the returned quorum specification.
+func (m *ClientMetrics) QuorumSpec(qspec QuorumSpec) QuorumSpec {
+	q := &metricsQSpec{QuorumSpec: qspec, metrics: m}
+	if nq, ok := qspec.(NodeQuorumSpec); ok {
+		return &metricsNodeQSpec{metricsQSpec: q, node: nq}
+	}
+	if eq, ok := qspec.(EvaluatorQuorumSpec); ok {
+		return &metricsEvaluatorQSpec{metricsQSpec: q, eval: eq}
+	}
+	return q
+}
+
+func (m *ClientMetrics) intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	d := time.Since(start)
+
+	invalid := false
+	if v, ok := reply.(*Value); ok && err == nil && v.GetC() != nil {
+		switch {
+		case m.writers != nil:
+			invalid = !m.writers.Verify(v)
+		case m.verifier != nil:
+			invalid = !Verify(m.verifier, v)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	node := m.node(cc.Target())
+	switch code := status.Code(err); {
+	case err == nil:
+		node.latency.observe(d)
+	case code != codes.Canceled && code != codes.DeadlineExceeded:
+		node.errors++
+	}
+	if invalid {
+		node.invalid++
+	}
+	return err
+}
+
+// observe records the outcome of a quorum call of method that returned err
+// after passing replies to the quorum function.
+func (m *ClientMetrics) observe(method string, replies int, err error) {
+	outcome := OutcomeFailed
+	switch err := err.(type) {
+	case nil:
+		outcome = OutcomeQuorum
+	case *NoValueError:
+		outcome = OutcomeNoValue
+	case QuorumCallError:
+		if err.Reason == "incomplete call" {
+			outcome = OutcomeIncomplete
+		} else {
+			outcome = OutcomeTimeout
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := m.stats(method)
+	stats.outcomes[outcome]++
+	if outcome == OutcomeQuorum {
+		stats.replies[replies]++
+	}
+}
+
+// observeCall records the outcome of a quorum call of method on c, if c's
+// quorum specification, or a quorum specification it wraps, was returned by
+// ClientMetrics.QuorumSpec.
+func (c *Configuration) observeCall(method string, replies int, err error) {
+	qspec := c.qspec
+	for {
+		switch q := qspec.(type) {
+		case interface{ clientMetrics() *ClientMetrics }:
+			q.clientMetrics().observe(method, replies, err)
+			return
+		case WrapperQuorumSpec:
+			qspec = q.Unwrap()
+		default:
+			return
+		}
+	}
+}
+
+func (m *ClientMetrics) node(addr string) *nodeStats {
+	node, found := m.nodes[addr]
+	if !found {
+		node = &nodeStats{latency: newHistogram()}
+		m.nodes[addr] = node
+	}
+	return node
+}
+
+func (m *ClientMetrics) stats(method string) *callStats {
+	s, found := m.calls[method]
+	if !found {
+		s = &callStats{outcomes: make(map[string]uint64), replies: make(map[int]uint64)}
+		m.calls[method] = s
+	}
+	return s
+}
+
+// Snapshot returns a snapshot of the metrics.
+func (m *ClientMetrics) Snapshot() ClientMetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := ClientMetricsSnapshot{
+		Nodes: make(map[string]NodeStats, len(m.nodes)),
+		Calls: make(map[string]CallStats, len(m.calls)),
+	}
+	for addr, node := range m.nodes {
+		s.Nodes[addr] = NodeStats{
+			Latency:           node.latency.snapshot(),
+			Errors:            node.errors,
+			InvalidSignatures: node.invalid,
+		}
+	}
+	for method, c := range m.calls {
+		cs := CallStats{Outcomes: make(map[string]uint64), RepliesNeeded: make(map[int]uint64)}
+		for outcome, n := range c.outcomes {
+			cs.Outcomes[outcome] = n
+		}
+		for replies, n := range c.replies {
+			cs.RepliesNeeded[replies] = n
+		}
+		s.Calls[method] = cs
+	}
+	return s
+}
+
+// WriteTo writes the metrics to w in the Prometheus text format.
+func (m *ClientMetrics) WriteTo(w io.Writer) (int64, error) {
+	s := m.Snapshot()
+	addrs := make([]string, 0, len(s.Nodes))
+	for addr := range s.Nodes {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	methods := make([]string, 0, len(s.Calls))
+	for method := range s.Calls {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	p := &promWriter{w: w}
+	p.header("byzq_client_node_duration_seconds", "histogram", "Latency of successful calls to each node.")
+	for _, addr := range addrs {
+		p.histogram("byzq_client_node_duration_seconds", s.Nodes[addr].Latency, "node", addr)
+	}
+	p.header("byzq_client_node_errors_total", "counter", "Number of failed calls to each node.")
+	for _, addr := range addrs {
+		p.sample("byzq_client_node_errors_total", float64(s.Nodes[addr].Errors), "node", addr)
+	}
+	p.header("byzq_client_invalid_signatures_total", "counter", "Number of replies with invalid signatures from each node.")
+	for _, addr := range addrs {
+		p.sample("byzq_client_invalid_signatures_total", float64(s.Nodes[addr].InvalidSignatures), "node", addr)
+	}
+	p.header("byzq_client_quorum_calls_total", "counter", "Number of quorum calls by outcome.")
+	for _, method := range methods {
+		for _, outcome := range []string{OutcomeQuorum, OutcomeNoValue, OutcomeIncomplete, OutcomeTimeout, OutcomeFailed} {
+			p.sample("byzq_client_quorum_calls_total", float64(s.Calls[method].Outcomes[outcome]), "method", method, "outcome", outcome)
+		}
+	}
+	p.header("byzq_client_quorum_replies_total", "counter", "Number of quorum calls by the number of replies needed for a quorum.")
+	for _, method := range methods {
+		replies := make([]int, 0, len(s.Calls[method].RepliesNeeded))
+		for r := range s.Calls[method].RepliesNeeded {
+			replies = append(replies, r)
+		}
+		sort.Ints(replies)
+		for _, r := range replies {
+			p.sample("byzq_client_quorum_replies_total", float64(s.Calls[method].RepliesNeeded[r]), "method", method, "replies", strconv.Itoa(r))
+		}
+	}
+	return p.n, p.err
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (m *ClientMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WriteTo(w)
+}
+
+// metricsQSpec uses the quorum functions of the wrapped quorum specification,
+// and makes configurations record the outcomes of their quorum calls in
+// metrics.
+type metricsQSpec struct {
+	QuorumSpec
+	metrics *ClientMetrics
+}
+
+func (q *metricsQSpec) Unwrap() QuorumSpec {
+	return q.QuorumSpec
+}
+
+func (q *metricsQSpec) clientMetrics() *ClientMetrics {
+	return q.metrics
+}
+
+func (q *metricsQSpec) ReadQF(replies []*Value) (*Content, bool, error) {
+	rq, ok := q.QuorumSpec.(ReadQuorumSpec)
+	if !ok {
+		return nil, false, fmt.Errorf("quorum specification %T has no read quorum function", q.QuorumSpec)
+	}
+	return rq.ReadQF(replies)
+}
+
+// Sign signs content with the wrapped quorum specification, so that
+// WriteNext can be used with metrics.
+func (q *metricsQSpec) Sign(content *Content) (*Value, error) {
+	s, ok := q.QuorumSpec.(signer)
+	if !ok {
+		return nil, fmt.Errorf("quorum specification %T cannot sign values", q.QuorumSpec)
+	}
+	return s.Sign(content)
+}
+
+// metricsNodeQSpec is a metricsQSpec wrapping a NodeQuorumSpec.
+type metricsNodeQSpec struct {
+	*metricsQSpec
+	node NodeQuorumSpec
+}
+
+func (q *metricsNodeQSpec) ReadNodeQF(replies []NodeValue) (*Content, bool, error) {
+	return q.node.ReadNodeQF(replies)
+}
+
+func (q *metricsNodeQSpec) ReadTimestampNodeQF(replies []NodeValue) (*Content, bool) {
+	return q.node.ReadTimestampNodeQF(replies)
+}
+
+func (q *metricsNodeQSpec) WriteNodeQF(req *Value, replies []NodeWriteResponse) (*WriteResponse, bool) {
+	return q.node.WriteNodeQF(req, replies)
+}
+
+// metricsEvaluatorQSpec is a metricsQSpec wrapping an EvaluatorQuorumSpec.
+type metricsEvaluatorQSpec struct {
+	*metricsQSpec
+	eval EvaluatorQuorumSpec
+}
+
+func (q *metricsEvaluatorQSpec) NewReadEvaluator() ReadEvaluator {
+	return q.eval.NewReadEvaluator()
+}
@@ -0,0 +1,218 @@
+package byzq
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestClientMetrics(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := NewClientMetrics(qspec.verifier)
+	faulty := func(i int) []ServerOption {
+		if i == 0 {
+			return []ServerOption{WithFaults(BitFlip)}
+		}
+		return nil
+	}
+	config, stop := startServers(t, 4, metrics.QuorumSpec(qspec), faulty, metrics.DialOption())
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := config.WriteNext(ctx, 1, "Winnie", "Poo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Read(ctx, &Key{Key: "Winnie"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Read(ctx, &Key{Key: "Piglet"}); err == nil {
+		t.Fatal("got nil error for read of unwritten key")
+	}
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	if _, err := config.Read(canceled, &Key{Key: "Winnie"}); err == nil {
+		t.Fatal("got nil error for read with canceled context")
+	}
+
+	// The faulty reply may return after the quorum calls have returned.
+	var (
+		s          ClientMetricsSnapshot
+		faultyAddr string
+	)
+	for deadline := time.Now().Add(5 * time.Second); faultyAddr == "" && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		s = metrics.Snapshot()
+		for addr, node := range s.Nodes {
+			if node.InvalidSignatures > 0 {
+				faultyAddr = addr
+			}
+		}
+	}
+	if faultyAddr == "" {
+		t.Fatal("got no invalid signatures, want one from the faulty node")
+	}
+	for addr, node := range s.Nodes {
+		if addr == faultyAddr && node.InvalidSignatures != 1 {
+			t.Errorf("got %d invalid signatures from faulty node %s, want 1", node.InvalidSignatures, addr)
+		}
+		if addr != faultyAddr && node.InvalidSignatures != 0 {
+			t.Errorf("got %d invalid signatures from correct node %s, want 0", node.InvalidSignatures, addr)
+		}
+	}
+
+	wantOutcomes := map[string]map[string]uint64{
+		"ReadTimestamp": {OutcomeQuorum: 1},
+		"Write":         {OutcomeQuorum: 1},
+		"Read":          {OutcomeQuorum: 1, OutcomeNoValue: 1, OutcomeTimeout: 1},
+	}
+	for method, want := range wantOutcomes {
+		for outcome, n := range want {
+			if got := s.Calls[method].Outcomes[outcome]; got != n {
+				t.Errorf("%s: got %d %s outcomes, want %d", method, got, outcome, n)
+			}
+		}
+		var quorums uint64
+		for replies, n := range s.Calls[method].RepliesNeeded {
+			if replies < qspec.q || replies > qspec.n {
+				t.Errorf("%s: got quorum with %d replies, want between %d and %d", method, replies, qspec.q, qspec.n)
+			}
+			quorums += n
+		}
+		if quorums != want[OutcomeQuorum] {
+			t.Errorf("%s: got %d quorums by replies needed, want %d", method, quorums, want[OutcomeQuorum])
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := metrics.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`byzq_client_invalid_signatures_total{node="` + faultyAddr + `"} 1`,
+		`byzq_client_quorum_calls_total{method="Read",outcome="novalue"} 1`,
+		`byzq_client_quorum_calls_total{method="Read",outcome="timeout"} 1`,
+		`byzq_client_quorum_calls_total{method="Write",outcome="quorum"} 1`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestClientMetricsConcurrentCalls(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qspec.SetReadRepair(true)
+	metrics := NewClientMetrics(nil)
+	stale := func(i int) []ServerOption {
+		if i == 0 {
+			return []ServerOption{WithFaults(Stale)}
+		}
+		return nil
+	}
+	config, stop := startServers(t, 4, metrics.QuorumSpec(qspec), stale, metrics.DialOption())
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, value := range []string{"Poo", "Tigger"} {
+		if _, err := config.WriteNext(ctx, 1, "Winnie", value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// concurrent reads share their argument, and repair the stale replica
+	const reads = 10
+	key := &Key{Key: "Winnie"}
+	var wg sync.WaitGroup
+	for i := 0; i < reads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c, err := config.ReadRegister(ctx, key); err != nil || c.GetValue() != "Tigger" {
+				t.Errorf("got %v, %v, want value %q", c, err, "Tigger")
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := metrics.Snapshot()
+	if got := s.Calls["Read"].Outcomes[OutcomeQuorum]; got != reads {
+		t.Errorf("got %d Read quorums, want %d", got, reads)
+	}
+	if got := s.Calls["Write"].Outcomes[OutcomeQuorum]; got != 2 {
+		t.Errorf("got %d Write quorums, want 2", got)
+	}
+}
+
+func TestClientMetricsDialOptionOnly(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := NewClientMetrics(qspec.verifier)
+	config, stop := startServers(t, 4, qspec, nil, metrics.DialOption())
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := config.WriteNext(ctx, 1, "Winnie", "Poo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.Read(ctx, &Key{Key: "Winnie"}); err != nil {
+		t.Fatal(err)
+	}
+	s := metrics.Snapshot()
+	if len(s.Nodes) == 0 {
+		t.Error("got no node statistics")
+	}
+	if len(s.Calls) != 0 {
+		t.Errorf("got quorum call statistics %v without the quorum specification", s.Calls)
+	}
+}
+
+func TestClientMetricsReadEvaluator(t *testing.T) {
+	signer, err := NewSigner(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := NewVerifier(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter := &countingVerifier{Verifier: verifier}
+	qspec, err := NewAuthDataQFromScheme(4, signer, counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1 := signContent(t, qspec, "Winnie", "Poo", 1, 0)
+	forged := &Value{C: &Content{Key: "Winnie", Value: "Eeyore", Timestamp: 9}, Algorithm: v1.Algorithm, Signature: v1.Signature}
+
+	metrics := NewClientMetrics(nil)
+	eq, ok := metrics.QuorumSpec(qspec).(EvaluatorQuorumSpec)
+	if !ok {
+		t.Fatal("got quorum specification without read evaluators")
+	}
+	e := eq.NewReadEvaluator()
+	before := atomic.LoadUint64(&counter.n)
+	var r ReadResult
+	for _, reply := range []*Value{forged, v1, v1, v1} {
+		r = e.Add(reply)
+	}
+	if c, quorum, err := r.QF(); !quorum || err != nil || !c.Equal(v1.C) {
+		t.Errorf("got %v, %t, %v, want %v, true, <nil>", c, quorum, err, v1.C)
+	}
+	// each reply is verified once by the wrapped incremental evaluator
+	if n := atomic.LoadUint64(&counter.n) - before; n != 4 {
+		t.Errorf("got %d verifications, want 4", n)
+	}
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"flag"
 	"fmt"
 	"log"
 	"math/rand"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -24,9 +26,20 @@ func main() {
 		saddrs   = flag.String("addrs", "", "server addresses separated by ','")
 		f        = flag.Int("f", 1, "fault tolerance, supported values f=1,2,3 (this is ignored if addrs is provided)")
 		noauth   = flag.Bool("noauth", false, "don't use authenticated channels")
-		generate = flag.Bool("generate", false, "generate public/private key-pair and save to file provided by -key")
+		generate = flag.Bool("generate", false, "generate public/private key-pair and save to files provided by -key and -pubkey")
+		alg      = flag.String("alg", "ecdsa_p256", "signature algorithm of the generated key-pair (ecdsa_p256, ecdsa_p384 or ed25519)")
 		writer   = flag.Bool("writer", false, "set this client to be writer only (default is reader only)")
-		keyFile  = flag.String("key", "priv-key.pem", "private key file to be used for signatures")
+		writerID = flag.Uint("id", 0, "writer id used to order concurrent writes (must be unique among writers)")
+		keyFile  = flag.String("key", "priv-key.pem", "private key file to be used for signatures (writer only)")
+		pubFile  = flag.String("pubkey", "pub-key.pem", "writer's public key file to be used for verification (reader only)")
+		wkeys    = flag.String("writerkeys", "", "public key files of the writers separated by ',', the i-th of which is the key of writer i; replies are verified with the key of the writer whose id they hold (default is to verify all replies with a single writer's key)")
+		atomic   = flag.Bool("atomic", false, "use atomic read semantics (default is regular)")
+		repair   = flag.Bool("repair", false, "repair stale replicas after each read")
+		tieBreak = flag.Bool("tiebreak", false, "choose the smallest of equivocating values (default is to fail the read)")
+		cache    = flag.Int("verifycache", 0, "number of signature verification results to cache (default is no cache)")
+		adaptive = flag.Bool("adaptive", false, "choose how to verify replies from measurements, and log the choice after each read")
+		metrics  = flag.String("metrics", "", "address to serve Prometheus metrics on at /metrics, e.g. localhost:9100 (default is no metrics)")
+		evidence = flag.String("evidence", "", "file to append evidence of Byzantine behavior to (default is no evidence)")
 	)
 
 	flag.Usage = func() {
@@ -38,10 +51,22 @@ func main() {
 
 	if *generate {
 		// Generate key file and exit.
-		err := byzq.GenerateKeyfile(*keyFile)
+		algorithm, err := byzq.ParseAlgorithm(*alg)
+		if err != nil {
+			dief("%v", err)
+		}
+		err = byzq.GenerateKeyfile(*keyFile, algorithm)
 		if err != nil {
 			dief("error generating public/private key-pair: %v", err)
 		}
+		key, err := byzq.ReadKeyfile(*keyFile)
+		if err != nil {
+			dief("error reading keyfile: %v", err)
+		}
+		err = byzq.WritePublicKeyfile(*pubFile, key.Public())
+		if err != nil {
+			dief("error writing public key: %v", err)
+		}
 		os.Exit(0)
 	}
 
@@ -67,6 +92,25 @@ func main() {
 	}
 	log.Printf("#addrs: %d (%v)", len(addrs), *saddrs)
 
+	var (
+		key crypto.Signer
+		pub crypto.PublicKey
+		err error
+	)
+	if *writer {
+		key, err = byzq.ReadKeyfile(*keyFile)
+		if err != nil {
+			dief("error reading keyfile: %v", err)
+		}
+		pub = key.Public()
+	} else {
+		// Readers only need the writer's public key.
+		pub, err = byzq.ReadPublicKeyfile(*pubFile)
+		if err != nil {
+			dief("error reading public key file: %v", err)
+		}
+	}
+
 	var secDialOption grpc.DialOption
 	if *noauth {
 		secDialOption = grpc.WithInsecure()
@@ -78,50 +122,97 @@ func main() {
 		secDialOption = grpc.WithTransportCredentials(clientCreds)
 	}
 
-	key, err := byzq.ReadKeyfile(*keyFile)
-	if err != nil {
-		dief("error reading keyfile: %v", err)
+	dialOpts := []grpc.DialOption{
+		grpc.WithBlock(),
+		grpc.WithTimeout(0 * time.Millisecond),
+		secDialOption,
+	}
+	var writers *byzq.WriterRegistry
+	if *wkeys != "" {
+		writers, err = byzq.ReadWriterRegistry(strings.Split(*wkeys, ",")...)
+		if err != nil {
+			dief("%v", err)
+		}
 	}
 
-	mgr, err := byzq.NewManager(
-		addrs,
-		byzq.WithGrpcDialOptions(
-			grpc.WithBlock(),
-			grpc.WithTimeout(0*time.Millisecond),
-			secDialOption,
-		),
-	)
+	var clientMetrics *byzq.ClientMetrics
+	if *metrics != "" {
+		verifier, err := byzq.NewVerifier(pub)
+		if err != nil {
+			dief("error creating verifier: %v", err)
+		}
+		clientMetrics = byzq.NewClientMetrics(verifier)
+		if writers != nil {
+			clientMetrics.SetWriters(writers)
+		}
+		dialOpts = append(dialOpts, clientMetrics.DialOption())
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", clientMetrics)
+		go func() {
+			log.Printf("metrics on http://%s/metrics", *metrics)
+			log.Fatal(http.ListenAndServe(*metrics, mux))
+		}()
+	}
+
+	mgr, err := byzq.NewManager(addrs, byzq.WithGrpcDialOptions(dialOpts...))
 	if err != nil {
 		dief("error creating manager: %v", err)
 	}
 	defer mgr.Close()
 
 	ids := mgr.NodeIDs()
-	qspec, err := byzq.NewAuthDataQ(len(ids), key, &key.PublicKey)
+	var qspec *byzq.AuthDataQ
+	if *writer {
+		qspec, err = byzq.NewAuthDataQ(len(ids), key, pub)
+	} else {
+		qspec, err = byzq.NewReadOnlyAuthDataQ(len(ids), pub)
+	}
 	if err != nil {
 		dief("error creating quorum specification: %v", err)
 	}
-	conf, err := mgr.NewConfiguration(ids, qspec)
+	if writers != nil {
+		qspec.SetWriters(writers)
+	}
+	if *atomic {
+		qspec.SetSemantics(byzq.Atomic)
+	}
+	qspec.SetReadRepair(*repair)
+	qspec.SetTieBreak(*tieBreak)
+	qspec.SetVerifyCache(*cache)
+	var adaptiveStrategy *byzq.AdaptiveStrategy
+	if *adaptive {
+		adaptiveStrategy = byzq.NewAdaptiveStrategy()
+		qspec.SetReadStrategy(adaptiveStrategy)
+	}
+	var confQSpec byzq.QuorumSpec = qspec
+	if *evidence != "" {
+		evidenceLog, err := byzq.OpenEvidenceLog(*evidence)
+		if err != nil {
+			dief("error opening evidence file: %v", err)
+		}
+		defer evidenceLog.Close()
+		suspicions := byzq.NewSuspicions(0)
+		suspicions.SetEvidenceLog(evidenceLog)
+		confQSpec = suspicions.QuorumSpec(qspec)
+	}
+	if clientMetrics != nil {
+		confQSpec = clientMetrics.QuorumSpec(confQSpec)
+	}
+	conf, err := mgr.NewConfiguration(ids, confQSpec)
 	if err != nil {
 		dief("error creating config: %v", err)
 	}
 
 	storageState := &byzq.Content{
-		Key:       "Hein",
-		Value:     "Meling",
-		Timestamp: -1,
+		Key:   "Hein",
+		Value: "Meling",
 	}
 
 	for {
 		if *writer {
 			// Writer client.
 			storageState.Value = strconv.Itoa(rand.Intn(1 << 8))
-			storageState.Timestamp++
-			signedState, err := qspec.Sign(storageState)
-			if err != nil {
-				dief("failed to sign message: %v", err)
-			}
-			ack, err := conf.Write(context.Background(), signedState)
+			ack, err := conf.WriteNext(context.Background(), uint32(*writerID), storageState.Key, storageState.Value)
 			if err != nil {
 				dief("error writing: %v", err)
 			}
@@ -129,11 +220,21 @@ func main() {
 			time.Sleep(15 * time.Second)
 		} else {
 			// Reader client.
-			val, err := conf.Read(context.Background(), &byzq.Key{Key: storageState.Key})
-			if err != nil {
+			val, err := conf.ReadRegister(context.Background(), &byzq.Key{Key: storageState.Key})
+			switch err.(type) {
+			case nil:
+				fmt.Println("ReadReturn: " + val.String())
+			case *byzq.NoValueError:
+				log.Printf("no value read: %v", err)
+			default:
 				dief("error reading: %v", err)
 			}
-			fmt.Println("ReadReturn: " + val.String())
+			if *repair {
+				log.Printf("read repairs issued: %d", qspec.ReadRepairs())
+			}
+			if adaptiveStrategy != nil {
+				log.Printf("read strategy: %v", adaptiveStrategy.Choice())
+			}
 			time.Sleep(10000 * time.Millisecond)
 		}
 	}
@@ -1,31 +1,33 @@
 package main
 
 import (
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
-	"sync"
-
-	"golang.org/x/net/context"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/relab/byzq"
 )
 
-type storage struct {
-	sync.RWMutex
-	state map[string]byzq.Value
-}
-
 func main() {
 	var (
-		port   = flag.Int("port", 8080, "port to listen on")
-		f      = flag.Int("f", 0, "fault tolerance")
-		noauth = flag.Bool("noauth", false, "don't use authenticated channels")
-		key    = flag.String("key", "", "public/private key file this server")
+		port       = flag.Int("port", 8080, "port to listen on")
+		f          = flag.Int("f", 0, "fault tolerance")
+		noauth     = flag.Bool("noauth", false, "don't use authenticated channels")
+		key        = flag.String("key", "", "public/private key file this server")
+		writerKeys = flag.String("writerkeys", "", "public key files of the writers separated by ',', the i-th of which is the key of writer i; writes not signed by the writer whose id they hold are rejected")
+		dataDir    = flag.String("datadir", "", "directory for durable storage (default is in-memory storage only)")
+		faults     = flag.String("faults", "", "Byzantine faults of the faulty servers separated by ',' (stale, bitflip, forge-ts, dropwrites, wrongack, late, equivocate); for testing only")
+		faulty     = flag.String("faulty", "", "indices of the faulty servers among the 3f+1 servers separated by ',' (default is the first f servers)")
+		faultDelay = flag.Duration("faultdelay", time.Second, "reply delay of faulty servers with the late fault")
+		metrics    = flag.String("metrics", "", "address to serve Prometheus metrics on at /metrics, e.g. localhost:9090; with -f, server i uses the port incremented by i (default is no metrics)")
 	)
 
 	flag.Usage = func() {
@@ -35,21 +37,99 @@ func main() {
 	}
 	flag.Parse()
 
+	writers, err := readWriterKeys(*writerKeys)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	faultOpts, err := parseFaults(*faults, *faultDelay)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
 	if *f > 0 {
 		// We are running only local since we have asked for 3f+1 servers.
+		faultySet, err := parseFaulty(*faulty, *f)
+		if err != nil {
+			log.Fatalln(err)
+		}
 		done := make(chan bool)
 		n := 3**f + 1
 		for i := 0; i < n; i++ {
-			go serve(*port+i, *key, *noauth)
+			opts := []byzq.ServerOption{byzq.WithWriters(writers)}
+			if faultySet[i] {
+				opts = append(opts, faultOpts...)
+				log.Printf("server %d is faulty: %s", *port+i, *faults)
+			}
+			go serve(*port+i, *key, *noauth, *dataDir, metricsAddr(*metrics, i), opts)
 		}
 		// Wait indefinitely.
 		<-done
 	}
 	// Run only one server.
-	serve(*port, *key, *noauth)
+	opts := append([]byzq.ServerOption{byzq.WithWriters(writers)}, faultOpts...)
+	serve(*port, *key, *noauth, *dataDir, *metrics, opts)
+}
+
+// metricsAddr returns the metrics address of server i, whose port is the
+// port of addr incremented by i.
+func metricsAddr(addr string, i int) string {
+	if addr == "" {
+		return ""
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		log.Fatalf("invalid metrics address %s: %v", addr, err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		log.Fatalf("invalid metrics address %s: %v", addr, err)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(p+i))
 }
 
-func serve(port int, keyFile string, noauth bool) {
+// parseFaults returns the server options for the comma-separated faults.
+func parseFaults(faults string, delay time.Duration) ([]byzq.ServerOption, error) {
+	if faults == "" {
+		return nil, nil
+	}
+	var fs []byzq.Fault
+	for _, name := range strings.Split(faults, ",") {
+		fault, err := byzq.ParseFault(name)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, fault)
+	}
+	return []byzq.ServerOption{byzq.WithFaults(fs...), byzq.WithFaultDelay(delay)}, nil
+}
+
+// parseFaulty returns the set of indices of the faulty servers.
+func parseFaulty(faulty string, f int) (map[int]bool, error) {
+	set := make(map[int]bool)
+	if faulty == "" {
+		for i := 0; i < f; i++ {
+			set[i] = true
+		}
+		return set, nil
+	}
+	for _, s := range strings.Split(faulty, ",") {
+		i, err := strconv.Atoi(s)
+		if err != nil || i < 0 || i > 3*f {
+			return nil, fmt.Errorf("invalid faulty server index: %s", s)
+		}
+		set[i] = true
+	}
+	return set, nil
+}
+
+func readWriterKeys(keyFiles string) (*byzq.WriterRegistry, error) {
+	if keyFiles == "" {
+		return nil, fmt.Errorf("required writer keys not provided")
+	}
+	return byzq.ReadWriterRegistry(strings.Split(keyFiles, ",")...)
+}
+
+func serve(port int, keyFile string, noauth bool, dataDir, metricsAddr string, opts []byzq.ServerOption) {
 	l, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
 	if err != nil {
 		log.Fatal(err)
@@ -58,35 +138,41 @@ func serve(port int, keyFile string, noauth bool) {
 	if keyFile == "" {
 		log.Fatalln("required server keys not provided")
 	}
-	opts := []grpc.ServerOption{}
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	opts = append(opts, byzq.WithServerLogger(logger))
 	if !noauth {
-		creds, err := credentials.NewServerTLSFromFile(keyFile+".crt", keyFile+".key")
+		cert, err := tls.LoadX509KeyPair(keyFile+".crt", keyFile+".key")
 		if err != nil {
 			log.Fatalf("failed to load credentials: %v", err)
 		}
-		opts = []grpc.ServerOption{grpc.Creds(creds)}
+		opts = append(opts, byzq.WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}))
 	}
-	grpcServer := grpc.NewServer(opts...)
-	smap := make(map[string]byzq.Value)
-	byzq.RegisterStorageServer(grpcServer, &storage{state: smap})
-	log.Printf("server %s running", l.Addr())
-	log.Fatal(grpcServer.Serve(l))
-}
-
-func (r *storage) Read(ctx context.Context, k *byzq.Key) (*byzq.Value, error) {
-	r.RLock()
-	value := r.state[k.Key]
-	r.RUnlock()
-	return &value, nil
-}
-
-func (r *storage) Write(ctx context.Context, v *byzq.Value) (*byzq.WriteResponse, error) {
-	wr := &byzq.WriteResponse{Timestamp: v.C.Timestamp}
-	r.Lock()
-	val, found := r.state[v.C.Key]
-	if !found || v.C.Timestamp > val.C.Timestamp {
-		r.state[v.C.Key] = *v
-	}
-	r.Unlock()
-	return wr, nil
+	if dataDir != "" {
+		// Each server in this process gets its own directory.
+		dir := filepath.Join(dataDir, strconv.Itoa(port))
+		store, err := byzq.OpenFileStore(dir)
+		if err != nil {
+			log.Fatalf("failed to open storage in %s: %v", dir, err)
+		}
+		keys := 0
+		store.Iterate(func(*byzq.Value) error { keys++; return nil })
+		log.Printf("recovered %d keys from %s", keys, dir)
+		opts = append(opts, byzq.WithStore(store))
+	}
+	if metricsAddr != "" {
+		metrics := byzq.NewServerMetrics(nil)
+		opts = append(opts, byzq.WithServerMetrics(metrics))
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics)
+		go func() {
+			log.Printf("server %d metrics on http://%s/metrics", port, metricsAddr)
+			log.Fatal(http.ListenAndServe(metricsAddr, mux))
+		}()
+	}
+	srv, err := byzq.NewServer(opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer srv.Stop()
+	log.Fatal(srv.Serve(l))
 }
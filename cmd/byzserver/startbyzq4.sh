@@ -4,10 +4,10 @@ set -e
 
 go build
 
-./byzserver -port=8080 -key keys/server &
-./byzserver -port=8081 -key keys/server &
-./byzserver -port=8082 -key keys/server &
-./byzserver -port=8083 -key keys/server &
+./byzserver -port=8080 -key keys/server -writerkeys ../byzclient/pub-key.pem &
+./byzserver -port=8081 -key keys/server -writerkeys ../byzclient/pub-key.pem &
+./byzserver -port=8082 -key keys/server -writerkeys ../byzclient/pub-key.pem &
+./byzserver -port=8083 -key keys/server -writerkeys ../byzclient/pub-key.pem &
 
 echo "running, enter to stop"
 
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/relab/byzq"
+)
+
+func main() {
+	var (
+		evidence   = flag.String("evidence", "", "evidence file written by a client")
+		writerKeys = flag.String("writerkeys", "", "public key files of the writers separated by ',', the i-th of which is the key of writer i")
+	)
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nVerifies evidence of Byzantine behavior offline.\n")
+		fmt.Fprintf(os.Stderr, "\nOptions:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *evidence == "" || *writerKeys == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	writers, err := byzq.ReadWriterRegistry(strings.Split(*writerKeys, ",")...)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	records, err := byzq.ReadEvidence(*evidence)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	claims, invalid := 0, 0
+	for i, e := range records {
+		switch err := e.Verify(writers); err {
+		case nil:
+			fmt.Printf("%d: valid %v\n", i+1, &e)
+		case byzq.ErrUnverifiedClaim:
+			claims++
+			fmt.Printf("%d: unverified %v: %v\n", i+1, &e, err)
+		default:
+			invalid++
+			fmt.Printf("%d: invalid %v: %v\n", i+1, &e, err)
+		}
+	}
+	fmt.Printf("%d records, %d valid, %d unverified, %d invalid\n", len(records), len(records)-claims-invalid, claims, invalid)
+	if invalid > 0 {
+		os.Exit(1)
+	}
+}
@@ -1,38 +1,195 @@
 package byzq
 
 import (
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
+	"strings"
 )
 
 // See https://golang.org/src/crypto/tls/generate_cert.go
 
-var curve = elliptic.P256()
+// Signer signs messages on behalf of a writer.
+type Signer interface {
+	// Algorithm returns the signature algorithm used by the signer.
+	Algorithm() Algorithm
+	// Sign returns the signature of msg.
+	Sign(msg []byte) ([]byte, error)
+}
+
+// Verifier verifies signatures produced by a writer's Signer.
+type Verifier interface {
+	// Algorithm returns the signature algorithm accepted by the verifier.
+	Algorithm() Algorithm
+	// Verify reports whether sig is a valid signature of msg.
+	Verify(msg, sig []byte) bool
+}
+
+// NewSigner returns a Signer for the given private key. Supported keys are
+// ECDSA keys on the P-256 and P-384 curves, and Ed25519 keys.
+func NewSigner(key crypto.Signer) (Signer, error) {
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		alg, err := curveAlgorithm(k.Curve)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsaSigner{alg: alg, key: k}, nil
+	case ed25519.PrivateKey:
+		return ed25519Signer(k), nil
+	}
+	return nil, fmt.Errorf("unsupported private key type: %T", key)
+}
+
+// NewVerifier returns a Verifier for the given public key. Supported keys are
+// ECDSA keys on the P-256 and P-384 curves, and Ed25519 keys.
+func NewVerifier(key crypto.PublicKey) (Verifier, error) {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		alg, err := curveAlgorithm(k.Curve)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsaVerifier{alg: alg, key: k}, nil
+	case ed25519.PublicKey:
+		if len(k) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 public key size: %d", len(k))
+		}
+		return ed25519Verifier(k), nil
+	}
+	return nil, fmt.Errorf("unsupported public key type: %T", key)
+}
+
+// ParseAlgorithm returns the algorithm with the given name, e.g. "ed25519".
+// The name is case insensitive.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	alg, ok := Algorithm_value[strings.ToUpper(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown signature algorithm: %s", name)
+	}
+	return Algorithm(alg), nil
+}
+
+func curveAlgorithm(c elliptic.Curve) (Algorithm, error) {
+	switch c {
+	case elliptic.P256():
+		return ECDSA_P256, nil
+	case elliptic.P384():
+		return ECDSA_P384, nil
+	}
+	return 0, fmt.Errorf("unsupported elliptic curve: %s", c.Params().Name)
+}
+
+// digest returns the hash of msg to be signed with the ECDSA algorithm alg.
+func digest(alg Algorithm, msg []byte) []byte {
+	if alg == ECDSA_P384 {
+		hash := sha512.Sum384(msg)
+		return hash[:]
+	}
+	hash := sha256.Sum256(msg)
+	return hash[:]
+}
+
+type ecdsaSigner struct {
+	alg Algorithm
+	key *ecdsa.PrivateKey
+}
+
+func (s *ecdsaSigner) Algorithm() Algorithm { return s.alg }
+
+// Sign returns the ECDSA signature of msg encoded as r||s, where r and s are
+// padded to the byte size of the curve.
+func (s *ecdsaSigner) Sign(msg []byte) ([]byte, error) {
+	r, ss, err := ecdsa.Sign(rand.Reader, s.key, digest(s.alg, msg))
+	if err != nil {
+		return nil, err
+	}
+	size := (s.key.Params().BitSize + 7) / 8
+	sig := make([]byte, 2*size)
+	r.FillBytes(sig[:size])
+	ss.FillBytes(sig[size:])
+	return sig, nil
+}
+
+type ecdsaVerifier struct {
+	alg Algorithm
+	key *ecdsa.PublicKey
+}
+
+func (v *ecdsaVerifier) Algorithm() Algorithm { return v.alg }
+
+func (v *ecdsaVerifier) Verify(msg, sig []byte) bool {
+	size := (v.key.Params().BitSize + 7) / 8
+	if len(sig) != 2*size {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig[:size])
+	s := new(big.Int).SetBytes(sig[size:])
+	return ecdsa.Verify(v.key, digest(v.alg, msg), r, s)
+}
+
+type ed25519Signer ed25519.PrivateKey
+
+func (s ed25519Signer) Algorithm() Algorithm { return ED25519 }
+
+func (s ed25519Signer) Sign(msg []byte) ([]byte, error) {
+	return ed25519.Sign(ed25519.PrivateKey(s), msg), nil
+}
+
+type ed25519Verifier ed25519.PublicKey
+
+func (v ed25519Verifier) Algorithm() Algorithm { return ED25519 }
+
+func (v ed25519Verifier) Verify(msg, sig []byte) bool {
+	return ed25519.Verify(ed25519.PublicKey(v), msg, sig)
+}
 
 // ParseKey takes a PEM formatted string and returns a private key.
-func ParseKey(pemKey string) (*ecdsa.PrivateKey, error) {
+// Both SEC 1 EC private keys and PKCS #8 private keys are accepted.
+func ParseKey(pemKey string) (crypto.Signer, error) {
 	block, _ := pem.Decode([]byte(pemKey))
 	//TODO(hein) Is this correct way? Does Decode return err?
 	if block == nil {
 		return nil, fmt.Errorf("no block to decode")
 	}
-	key, err := x509.ParseECPrivateKey(block.Bytes)
-	//TODO(Hein) Do we need to create a new error here?
+	var (
+		key interface{}
+		err error
+	)
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported pem block type: %s", block.Type)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse key from pem block: %v\n %v", err, key)
+		return nil, fmt.Errorf("failed to parse key from pem block: %v", err)
 	}
-	return key, nil
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type: %T", key)
+	}
+	if _, err = NewSigner(signer); err != nil {
+		return nil, err
+	}
+	return signer, nil
 }
 
 // ReadKeyfile reads the provided keyFile and returns the private key
 // stored in the file.
-func ReadKeyfile(keyFile string) (*ecdsa.PrivateKey, error) {
+func ReadKeyfile(keyFile string) (crypto.Signer, error) {
 	b, err := ioutil.ReadFile(keyFile)
 	if err != nil {
 		return nil, err
@@ -40,31 +197,106 @@ func ReadKeyfile(keyFile string) (*ecdsa.PrivateKey, error) {
 	return ParseKey(string(b))
 }
 
-// GenerateKeyfile generates a private key and writes it to the given keyFile.
+// GenerateKeyfile generates a private key for the given signature algorithm
+// and writes it to the given keyFile.
 // Note that if the file exists it will be overwritten.
-func GenerateKeyfile(keyFile string) error {
+func GenerateKeyfile(keyFile string, alg Algorithm) error {
 	f, err := os.OpenFile(keyFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	block, err := generateKey(alg)
+	if err != nil {
+		return err
+	}
+
+	err = pem.Encode(f, block)
+	if err != nil {
+		return fmt.Errorf("failed to PEM encode key: %v", err)
+	}
+	return nil
+}
+
+// generateKey generates a private key for alg and returns it as a PEM block.
+func generateKey(alg Algorithm) (*pem.Block, error) {
+	switch alg {
+	case ECDSA_P256, ECDSA_P384:
+		curve := elliptic.P256()
+		if alg == ECDSA_P384 {
+			curve = elliptic.P384()
+		}
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		ec, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal key to pem block: %v", err)
+		}
+		return &pem.Block{Type: "EC PRIVATE KEY", Bytes: ec}, nil
+	case ED25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal key to pem block: %v", err)
+		}
+		return &pem.Block{Type: "PRIVATE KEY", Bytes: der}, nil
+	}
+	return nil, fmt.Errorf("unsupported signature algorithm: %v", alg)
+}
+
+// ParsePublicKey takes a PEM formatted string and returns a public key.
+func ParsePublicKey(pemKey string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, fmt.Errorf("no block to decode")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key from pem block: %v", err)
+	}
+	if _, err = NewVerifier(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ReadPublicKeyfile reads the provided pubFile and returns the public key
+// stored in the file.
+func ReadPublicKeyfile(pubFile string) (crypto.PublicKey, error) {
+	b, err := ioutil.ReadFile(pubFile)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePublicKey(string(b))
+}
+
+// WritePublicKeyfile writes the public key to the given pubFile in PEM format,
+// so that it can be distributed to readers and replicas without the private key.
+// Note that if the file exists it will be overwritten.
+func WritePublicKeyfile(pubFile string, pub crypto.PublicKey) error {
+	f, err := os.OpenFile(pubFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 
-	ec, err := x509.MarshalECPrivateKey(key)
+	der, err := x509.MarshalPKIXPublicKey(pub)
 	if err != nil {
-		return fmt.Errorf("failed to marshal key to pem block: %v\n %v", err, key)
+		return fmt.Errorf("failed to marshal public key to pem block: %v", err)
 	}
 
 	err = pem.Encode(f, &pem.Block{
-		Type:  "EC PRIVATE KEY",
-		Bytes: ec,
+		Type:  "PUBLIC KEY",
+		Bytes: der,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to PEM encode key: %v\n %v", err, key)
+		return fmt.Errorf("failed to PEM encode public key: %v", err)
 	}
 	return nil
 }
@@ -0,0 +1,164 @@
+package byzq
+
+import (
+	"crypto/ecdsa"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var algorithms = []Algorithm{ECDSA_P256, ECDSA_P384, ED25519}
+
+func TestPublicKeyfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "byzq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pubFile := filepath.Join(dir, "pub-key.pem")
+	if err = WritePublicKeyfile(pubFile, &priv.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	key, err := ReadPublicKeyfile(pubFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		t.Fatalf("got public key of type %T, want %T", key, &priv.PublicKey)
+	}
+	if pub.X.Cmp(priv.PublicKey.X) != 0 || pub.Y.Cmp(priv.PublicKey.Y) != 0 {
+		t.Errorf("got public key %v, want %v", pub, &priv.PublicKey)
+	}
+
+	if _, err = ParsePublicKey(pemKeyData); err == nil {
+		t.Error("got nil error when parsing private key as public key")
+	}
+	if _, err = ParsePublicKey("not a key"); err == nil {
+		t.Error("got nil error when parsing non-PEM data")
+	}
+}
+
+func TestKeyfileAlgorithms(t *testing.T) {
+	dir, err := ioutil.TempDir("", "byzq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, alg := range algorithms {
+		t.Run(alg.String(), func(t *testing.T) {
+			keyFile := filepath.Join(dir, alg.String()+"-priv.pem")
+			pubFile := filepath.Join(dir, alg.String()+"-pub.pem")
+			if err := GenerateKeyfile(keyFile, alg); err != nil {
+				t.Fatal(err)
+			}
+			key, err := ReadKeyfile(keyFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = WritePublicKeyfile(pubFile, key.Public()); err != nil {
+				t.Fatal(err)
+			}
+			pub, err := ReadPublicKeyfile(pubFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			writer, err := NewAuthDataQ(4, key, key.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			reader, err := NewReadOnlyAuthDataQ(4, pub)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signed, err := writer.Sign(myVal.C)
+			if err != nil {
+				t.Fatal("Failed to sign message")
+			}
+			if signed.Algorithm != alg {
+				t.Errorf("got algorithm %v, want %v", signed.Algorithm, alg)
+			}
+			reply, byzquorum, err := reader.SequentialVerifyReadQF([]*Value{signed, signed, signed})
+			if !byzquorum || err != nil {
+				t.Errorf("got %t, %v, want %t, nil", byzquorum, err, true)
+			}
+			if !reply.Equal(myVal.C) {
+				t.Errorf("got %v, want %v as quorum reply", reply, myVal.C)
+			}
+		})
+	}
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	for _, alg := range algorithms {
+		got, err := ParseAlgorithm(alg.String())
+		if err != nil || got != alg {
+			t.Errorf("ParseAlgorithm(%q) = %v, %v, want %v", alg.String(), got, err, alg)
+		}
+	}
+	if got, err := ParseAlgorithm("ed25519"); err != nil || got != ED25519 {
+		t.Errorf("ParseAlgorithm(%q) = %v, %v, want %v", "ed25519", got, err, ED25519)
+	}
+	if _, err := ParseAlgorithm("rsa"); err == nil {
+		t.Error("got nil error for unknown algorithm")
+	}
+}
+
+func TestReadOnlyAuthDataQ(t *testing.T) {
+	writer, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := NewReadOnlyAuthDataQ(4, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = reader.Sign(myVal.C); err == nil {
+		t.Error("got nil error when signing with read-only quorum specification")
+	}
+	signed, err := writer.Sign(myVal.C)
+	if err != nil {
+		t.Fatal("Failed to sign message")
+	}
+	reply, byzquorum, err := reader.SequentialVerifyReadQF([]*Value{signed, signed, signed})
+	if !byzquorum || err != nil {
+		t.Errorf("got %t, %v, want %t, nil", byzquorum, err, true)
+	}
+	if !reply.Equal(myVal.C) {
+		t.Errorf("got %v, want %v as quorum reply", reply, myVal.C)
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	for _, alg := range algorithms {
+		block, err := generateKey(alg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		key, err := ParseKey(string(pem.EncodeToMemory(block)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		qspec, err := NewAuthDataQ(4, key, key.Public())
+		if err != nil {
+			b.Fatal(err)
+		}
+		signed, err := qspec.Sign(myVal.C)
+		if err != nil {
+			b.Fatal("Failed to sign message")
+		}
+		b.Run(fmt.Sprintf("Verify(%v)", alg), func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				qspec.verify(signed)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package byzq
+
+import "fmt"
+
+// EquivocationError is returned by the read quorum functions if verified
+// replies hold different values for the same key, timestamp and writer ID.
+// A correct writer never signs two such values, so the writer's key must be
+// compromised. Since the replies cannot be ordered, no value is returned
+// unless tie-breaking is enabled with SetTieBreak.
+type EquivocationError struct {
+	Key       string
+	Timestamp int64
+	WriterID  uint32
+	// Values holds two of the different signed values.
+	Values []*Value
+}
+
+func (e *EquivocationError) Error() string {
+	return fmt.Sprintf("writer %d equivocated: different values for key %q with timestamp %d", e.WriterID, e.Key, e.Timestamp)
+}
+
+// SetTieBreak enables or disables deterministic tie-breaking among
+// equivocating values. With tie-breaking enabled, the read quorum functions
+// choose the value that is smallest in lexicographic order instead of
+// failing with an EquivocationError, so that all readers of the same values
+// choose the same one. The default is to fail.
+func (aq *AuthDataQ) SetTieBreak(enable bool) {
+	aq.tieBreak = enable
+}
+
+// highest returns the reply with the highest content among the non-nil
+// replies, or an EquivocationError if another reply has different content
+// for the same write as the highest, and tie-breaking is disabled.
+func (aq *AuthDataQ) highest(replies []*Value) (*Value, error) {
+	var h highestValue
+	for _, reply := range replies {
+		h.add(reply, aq.tieBreak)
+	}
+	return h.result()
+}
+
+// highestValue tracks the reply with the highest content among the replies
+// passed to add, and a reply with different content for the same write.
+type highestValue struct {
+	highest, conflict *Value
+}
+
+// add adds reply, which is ignored if it is nil. With tieBreak, the smallest
+// of the values with different content for the same write is kept instead of
+// recording a conflict.
+func (h *highestValue) add(reply *Value, tieBreak bool) {
+	if reply == nil {
+		return
+	}
+	switch {
+	case h.highest == nil || reply.GetC().Newer(h.highest.GetC()):
+		h.highest, h.conflict = reply, nil
+	case sameWrite(reply.GetC(), h.highest.GetC()) && !reply.C.Equal(h.highest.C):
+		if !tieBreak {
+			h.conflict = reply
+		} else if reply.C.Value < h.highest.C.Value {
+			h.highest = reply
+		}
+	}
+}
+
+// result returns the highest reply added, or an EquivocationError if there
+// is a conflict.
+func (h *highestValue) result() (*Value, error) {
+	if h.conflict != nil {
+		return nil, &EquivocationError{
+			Key:       h.highest.C.Key,
+			Timestamp: h.highest.C.Timestamp,
+			WriterID:  h.highest.C.WriterID,
+			Values:    []*Value{h.highest, h.conflict},
+		}
+	}
+	return h.highest, nil
+}
+
+// sameWrite reports whether a and b have the same key, timestamp and
+// writer ID, and therefore must have been signed by the same write.
+func sameWrite(a, b *Content) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	return a.Key == b.Key && a.Timestamp == b.Timestamp && a.WriterID == b.WriterID
+}
@@ -0,0 +1,71 @@
+package byzq
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEquivocation(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := signContent(t, qspec, "Winnie", "Poo", 3, 1)
+	b := signContent(t, qspec, "Winnie", "Tigger", 3, 1)
+	older := signContent(t, qspec, "Winnie", "Eeyore", 2, 1)
+	newer := signContent(t, qspec, "Winnie", "Piglet", 4, 1)
+	other := signContent(t, qspec, "Winnie", "Roo", 3, 2)
+
+	equivocationTests := []struct {
+		name     string
+		replies  []*Value
+		tieBreak bool
+		expected *Content // nil if an EquivocationError is expected
+	}{
+		{"equivocation", []*Value{a, b, a}, false, nil},
+		{"equivocation reversed", []*Value{b, a, b}, false, nil},
+		{"equivocation with older", []*Value{older, a, b}, false, nil},
+		{"tie-break", []*Value{a, b, a}, true, a.C},
+		{"tie-break reversed", []*Value{b, a, b}, true, a.C},
+		{"newer value", []*Value{a, b, newer}, false, newer.C},
+		{"other writer", []*Value{a, other, a}, false, other.C},
+	}
+
+	for _, test := range equivocationTests {
+		qspec.SetTieBreak(test.tieBreak)
+		qfuncs := []struct {
+			name string
+			qf   func([]*Value) (*Content, bool, error)
+		}{
+			{"SequentialVerifyReadQF", qspec.SequentialVerifyReadQF},
+			{"ConcurrentVerifyIndexChanReadQF", qspec.ConcurrentVerifyIndexChanReadQF},
+			{"VerfiyLastReplyFirstReadQF", qspec.VerfiyLastReplyFirstReadQF},
+			{"ConcurrentVerifyWGReadQF", qspec.ConcurrentVerifyWGReadQF},
+		}
+		for _, qfunc := range qfuncs {
+			t.Run(fmt.Sprintf("%s %s", qfunc.name, test.name), func(t *testing.T) {
+				replies := append([]*Value(nil), test.replies...)
+				reply, byzquorum, err := qfunc.qf(replies)
+				if !byzquorum {
+					t.Fatalf("got %t, want %t", byzquorum, true)
+				}
+				if test.expected != nil {
+					if err != nil || !reply.Equal(test.expected) {
+						t.Errorf("got %v, %v, want %v, nil", reply, err, test.expected)
+					}
+					return
+				}
+				ee, ok := err.(*EquivocationError)
+				if !ok {
+					t.Fatalf("got %v, %v, want equivocation error", reply, err)
+				}
+				if reply != nil {
+					t.Errorf("got %v, want nil", reply)
+				}
+				if ee.Key != "Winnie" || ee.Timestamp != 3 || ee.WriterID != 1 || len(ee.Values) != 2 || ee.Values[0].C.Equal(ee.Values[1].C) {
+					t.Errorf("got %v with values %v, want values of writer 1 with timestamp 3", ee, ee.Values)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package byzq
+
+// ReadEvaluator evaluates the replies of a single Read quorum call as they
+// arrive, keeping state between replies so that earlier replies are not
+// evaluated again. A ReadEvaluator is used by one quorum call at a time.
+type ReadEvaluator interface {
+	// Add evaluates reply, and returns the result for all replies added so
+	// far.
+	Add(reply *Value) ReadResult
+}
+
+// EvaluatorQuorumSpec is implemented by quorum specifications that evaluate
+// the replies of a Read quorum call with a ReadEvaluator. Read creates a
+// new evaluator for each quorum call, and uses it instead of ReadQF.
+type EvaluatorQuorumSpec interface {
+	QuorumSpec
+
+	// NewReadEvaluator returns an evaluator for a new Read quorum call.
+	NewReadEvaluator() ReadEvaluator
+}
+
+// IncrementalVerify verifies each reply exactly once when used by the Read
+// quorum call, and keeps the highest verified reply and the number of valid
+// replies between replies. Given a slice of replies, e.g. by ReadQF, it
+// verifies all of them, like SequentialVerify. It is the default strategy.
+var IncrementalVerify ReadStrategy = incrementalVerify{}
+
+type incrementalVerify struct{}
+
+func (incrementalVerify) String() string { return "incremental" }
+
+func (incrementalVerify) Evaluate(aq *AuthDataQ, replies []*Value) ReadResult {
+	if len(replies) <= aq.q {
+		// not enough replies yet; need at least bq.q=(n+2f)/2 replies
+		return ReadResult{Replies: len(replies)}
+	}
+	e := incrementalVerify{}.newEvaluator(aq)
+	var r ReadResult
+	for _, reply := range replies {
+		r = e.Add(reply)
+	}
+	return r
+}
+
+// evaluatorStrategy is implemented by read strategies with an evaluator of
+// their own.
+type evaluatorStrategy interface {
+	ReadStrategy
+	newEvaluator(aq *AuthDataQ) ReadEvaluator
+}
+
+func (incrementalVerify) newEvaluator(aq *AuthDataQ) ReadEvaluator {
+	return &incrementalEvaluator{aq: aq}
+}
+
+// incrementalEvaluator verifies each reply once when it is added.
+type incrementalEvaluator struct {
+	aq      *AuthDataQ
+	replies int
+	invalid int
+	highest highestValue
+}
+
+func (e *incrementalEvaluator) Add(reply *Value) ReadResult {
+	e.replies++
+	switch {
+	case reply.GetC() == nil:
+		// an empty reply is valid, but holds no value
+	case e.aq.verify(reply):
+		e.highest.add(reply, e.aq.tieBreak)
+	default:
+		e.invalid++
+	}
+	r := ReadResult{Replies: e.replies, Invalid: e.invalid}
+	if e.replies-e.invalid <= e.aq.q {
+		// not enough valid replies yet
+		return r
+	}
+	r.Quorum = true
+	r.Value, r.Err = e.highest.result()
+	return r
+}
+
+// strategyEvaluator evaluates the replies added so far with a strategy
+// that has no evaluator of its own.
+type strategyEvaluator struct {
+	aq       *AuthDataQ
+	strategy ReadStrategy
+	replies  []*Value
+}
+
+func (e *strategyEvaluator) Add(reply *Value) ReadResult {
+	e.replies = append(e.replies, reply)
+	return e.strategy.Evaluate(e.aq, e.replies)
+}
+
+// NewReadEvaluator returns an evaluator for a new Read quorum call, using
+// the read strategy selected with SetReadStrategy. Strategies other than
+// IncrementalVerify evaluate all replies received so far for every reply,
+// like ReadQF.
+func (aq *AuthDataQ) NewReadEvaluator() ReadEvaluator {
+	s := aq.readStrategy()
+	if es, ok := s.(evaluatorStrategy); ok {
+		return es.newEvaluator(aq)
+	}
+	return &strategyEvaluator{aq: aq, strategy: s}
+}
@@ -0,0 +1,151 @@
+package byzq
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestReadEvaluator(t *testing.T) {
+	signer, err := NewSigner(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := NewVerifier(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter := &countingVerifier{Verifier: verifier}
+	qspec, err := NewAuthDataQFromScheme(4, signer, counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1 := signContent(t, qspec, "Winnie", "Poo", 1, 0)
+	v2 := signContent(t, qspec, "Winnie", "Poop", 2, 0)
+	forged := &Value{C: &Content{Key: "Winnie", Value: "Eeyore", Timestamp: 9}, Algorithm: v1.Algorithm, Signature: v1.Signature}
+
+	evaluatorTests := [][]*Value{
+		{v1, v1, v1},
+		{v1, forged, v2, {}, v1},
+		{forged, forged, v1, v1},
+		{{}, {}, {}},
+	}
+	for _, s := range readStrategies {
+		qspec.SetReadStrategy(s)
+		for i, replies := range evaluatorTests {
+			t.Run(fmt.Sprintf("%v/%d", s, i), func(t *testing.T) {
+				// the evaluator must agree with the strategy given a growing
+				// slice of replies
+				want := append([]*Value(nil), replies...)
+				e := qspec.NewReadEvaluator()
+				for j, reply := range replies {
+					got := e.Add(reply)
+					w := s.Evaluate(qspec, want[:j+1])
+					gc, gq, gerr := got.QF()
+					wc, wq, werr := w.QF()
+					if gq != wq || !gc.Equal(wc) || (gerr == nil) != (werr == nil) || (gq && got.Invalid != w.Invalid) {
+						t.Errorf("reply %d: got %v, %t, %v, want %v, %t, %v", j, gc, gq, gerr, wc, wq, werr)
+					}
+				}
+			})
+		}
+	}
+
+	// each reply is verified exactly once by the incremental evaluator
+	qspec.SetReadStrategy(nil)
+	e := qspec.NewReadEvaluator()
+	before := atomic.LoadUint64(&counter.n)
+	for _, reply := range []*Value{v1, forged, v2, {}, v1} {
+		e.Add(reply)
+	}
+	if n := atomic.LoadUint64(&counter.n) - before; n != 4 {
+		t.Errorf("got %d verifications, want 4", n)
+	}
+}
+
+func TestReadEvaluatorCluster(t *testing.T) {
+	signer, err := NewSigner(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := NewVerifier(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter := &countingVerifier{Verifier: verifier}
+	qspec, err := NewAuthDataQFromScheme(4, signer, counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, stop := startServers(t, 4, qspec, nil)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := config.WriteNext(ctx, 1, "Winnie", "Poo"); err != nil {
+		t.Fatal(err)
+	}
+	before := atomic.LoadUint64(&counter.n)
+	c, err := config.Read(ctx, &Key{Key: "Winnie"})
+	if err != nil || c.GetValue() != "Poo" {
+		t.Fatalf("got %v, %v, want value %q", c, err, "Poo")
+	}
+	// a quorum is found after q+1=3 replies, each verified once
+	if n := atomic.LoadUint64(&counter.n) - before; n != 3 {
+		t.Errorf("got %d verifications, want 3", n)
+	}
+}
+
+func BenchmarkReadEvaluator(b *testing.B) {
+	const n = 13
+	qspec, err := NewAuthDataQ(n, priv, &priv.PublicKey)
+	if err != nil {
+		b.Fatal(err)
+	}
+	v, err := qspec.Sign(myVal.C)
+	if err != nil {
+		b.Fatal("Failed to sign message")
+	}
+	forged := &Value{C: myVal2.C, Algorithm: v.Algorithm, Signature: v.Signature}
+	// the first f replies are invalid, so that a quorum is only found after
+	// all replies are received
+	replies := make([]*Value, n)
+	for i := range replies {
+		replies[i] = v
+		if i < qspec.f {
+			replies[i] = forged
+		}
+	}
+
+	for _, s := range []ReadStrategy{IncrementalVerify, SequentialVerify, VerifyLastReplyFirst, VerifyHighestFirst, NewAdaptiveStrategy()} {
+		qspec.SetReadStrategy(s)
+		// a Read quorum call passing a growing slice of replies to ReadQF
+		b.Run(fmt.Sprintf("ReadQF(%d) %v", n, s), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				received := make([]*Value, 0, n)
+				for _, reply := range replies {
+					received = append(received, reply)
+					if _, quorum, _ := qspec.ReadQF(received); quorum {
+						break
+					}
+				}
+			}
+		})
+		// a Read quorum call passing each reply once to an evaluator
+		b.Run(fmt.Sprintf("NewReadEvaluator(%d) %v", n, s), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				e := qspec.NewReadEvaluator()
+				for _, reply := range replies {
+					if e.Add(reply).Quorum {
+						break
+					}
+				}
+			}
+		})
+	}
+}
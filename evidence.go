@@ -0,0 +1,195 @@
+package byzq
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// EvidenceKind is a kind of misbehavior recorded as evidence.
+type EvidenceKind int
+
+const (
+	// Equivocation is evidence that a writer signed two different values
+	// for the same key, timestamp and writer ID.
+	Equivocation EvidenceKind = iota
+	// Unacknowledged is a claim that a replica returned a value signed for
+	// the client's own writer ID with a timestamp the client never wrote,
+	// and that therefore no quorum acknowledged. Unlike equivocation, it is
+	// not provable from the signed value alone: the highest timestamp
+	// written is reported by the client that recorded the evidence, and is
+	// not signed.
+	Unacknowledged
+)
+
+var evidenceKindNames = []string{"equivocation", "unacknowledged"}
+
+func (k EvidenceKind) String() string {
+	if k < 0 || int(k) >= len(evidenceKindNames) {
+		return "unknown"
+	}
+	return evidenceKindNames[k]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k EvidenceKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (k *EvidenceKind) UnmarshalText(text []byte) error {
+	for i, name := range evidenceKindNames {
+		if name == string(text) {
+			*k = EvidenceKind(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown evidence kind: %s", text)
+}
+
+// Evidence is a self-contained record of misbehavior, holding the signed
+// values and the IDs of the nodes that sent them. Evidence can be checked
+// offline with Verify, given the public keys of the writers.
+type Evidence struct {
+	Kind EvidenceKind `json:"kind"`
+	Time time.Time    `json:"time"`
+	// NodeIDs holds the ID of the node that sent each value.
+	NodeIDs []uint32 `json:"node_ids"`
+	Values  []*Value `json:"values"`
+	// Written is the highest timestamp written by the client for the key
+	// and writer ID of the value of Unacknowledged evidence, as reported by
+	// the client.
+	Written int64 `json:"written,omitempty"`
+}
+
+// ErrUnverifiedClaim is returned by Verify for Unacknowledged evidence whose
+// value is validly signed and newer than Written. Since Written is reported
+// by the client that recorded the evidence, the evidence only shows
+// misbehavior to those who trust that client.
+var ErrUnverifiedClaim = errors.New("unverified claim: the written timestamp is reported by the client")
+
+func (e *Evidence) String() string {
+	return fmt.Sprintf("%v from nodes %v at %v", e.Kind, e.NodeIDs, e.Time.Format(time.RFC3339))
+}
+
+// Verify returns nil if e proves misbehavior, that is, if its values are
+// signed by the writers whose IDs they hold and are in conflict according to
+// the kind of evidence. Unacknowledged evidence cannot be proven, and
+// ErrUnverifiedClaim is returned instead of nil. Otherwise, the reason why e
+// is not valid is returned.
+func (e *Evidence) Verify(writers *WriterRegistry) error {
+	if len(e.NodeIDs) != len(e.Values) {
+		return fmt.Errorf("got %d node IDs for %d values", len(e.NodeIDs), len(e.Values))
+	}
+	for i, v := range e.Values {
+		if !writers.Verify(v) {
+			return fmt.Errorf("value %d is not signed by a known writer", i)
+		}
+	}
+	switch e.Kind {
+	case Equivocation:
+		if len(e.Values) != 2 {
+			return fmt.Errorf("got %d values, want 2", len(e.Values))
+		}
+		a, b := e.Values[0].C, e.Values[1].C
+		if a.Key != b.Key || a.Timestamp != b.Timestamp || a.WriterID != b.WriterID {
+			return fmt.Errorf("values are for different writes")
+		}
+		if a.Equal(b) {
+			return fmt.Errorf("values are equal")
+		}
+	case Unacknowledged:
+		if len(e.Values) != 1 {
+			return fmt.Errorf("got %d values, want 1", len(e.Values))
+		}
+		if ts := e.Values[0].C.Timestamp; ts <= e.Written {
+			return fmt.Errorf("timestamp %d was written", ts)
+		}
+		return ErrUnverifiedClaim
+	default:
+		return fmt.Errorf("unknown evidence kind: %v", e.Kind)
+	}
+	return nil
+}
+
+// EvidenceLog collects evidence, and optionally persists it to a file with
+// one JSON encoded record per line. It is safe for concurrent use.
+type EvidenceLog struct {
+	mu       sync.Mutex
+	f        *os.File
+	evidence []Evidence
+}
+
+// NewEvidenceLog returns an evidence log that keeps evidence in memory only.
+func NewEvidenceLog() *EvidenceLog {
+	return &EvidenceLog{}
+}
+
+// OpenEvidenceLog returns an evidence log that appends evidence to the file
+// at path, creating the file if needed. Evidence already in the file is not
+// loaded; use ReadEvidence to read it.
+func OpenEvidenceLog(path string) (*EvidenceLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &EvidenceLog{f: f}, nil
+}
+
+// Record adds e to the log. If the log has a file, e is appended to the file
+// and synced to disk before Record returns.
+func (l *EvidenceLog) Record(e Evidence) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.evidence = append(l.evidence, e)
+	if l.f == nil {
+		return nil
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := l.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return l.f.Sync()
+}
+
+// Evidence returns the evidence recorded since the log was opened.
+func (l *EvidenceLog) Evidence() []Evidence {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Evidence(nil), l.evidence...)
+}
+
+// Close closes the file of the log, if any.
+func (l *EvidenceLog) Close() error {
+	if l.f == nil {
+		return nil
+	}
+	return l.f.Close()
+}
+
+// ReadEvidence returns the evidence stored in the file at path.
+func ReadEvidence(path string) ([]Evidence, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var evidence []Evidence
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<24)
+	for line := 1; s.Scan(); line++ {
+		var e Evidence
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		evidence = append(evidence, e)
+	}
+	return evidence, s.Err()
+}
@@ -0,0 +1,156 @@
+package byzq
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func signContent(t *testing.T, qspec *AuthDataQ, key, value string, ts int64, writerID uint32) *Value {
+	v, err := qspec.Sign(&Content{Key: key, Value: value, Timestamp: ts, WriterID: writerID})
+	if err != nil {
+		t.Fatal("Failed to sign message")
+	}
+	return v
+}
+
+func TestEvidenceVerify(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writers := testWriters(qspec.verifier)
+	a := signContent(t, qspec, "Winnie", "Poo", 3, 1)
+	b := signContent(t, qspec, "Winnie", "Tigger", 3, 1)
+	later := signContent(t, qspec, "Winnie", "Tigger", 4, 1)
+	forged := &Value{C: b.C, Algorithm: a.Algorithm, Signature: a.Signature}
+
+	evidenceTests := []struct {
+		name  string
+		e     Evidence
+		valid bool
+		claim bool
+	}{
+		{"equivocation", Evidence{Kind: Equivocation, NodeIDs: []uint32{1, 2}, Values: []*Value{a, b}}, true, false},
+		{"equal values", Evidence{Kind: Equivocation, NodeIDs: []uint32{1, 2}, Values: []*Value{a, a}}, false, false},
+		{"different timestamps", Evidence{Kind: Equivocation, NodeIDs: []uint32{1, 2}, Values: []*Value{a, later}}, false, false},
+		{"forged signature", Evidence{Kind: Equivocation, NodeIDs: []uint32{1, 2}, Values: []*Value{a, forged}}, false, false},
+		{"missing node ID", Evidence{Kind: Equivocation, NodeIDs: []uint32{1}, Values: []*Value{a, b}}, false, false},
+		{"unacknowledged", Evidence{Kind: Unacknowledged, NodeIDs: []uint32{1}, Values: []*Value{later}, Written: 3}, false, true},
+		{"forged unacknowledged", Evidence{Kind: Unacknowledged, NodeIDs: []uint32{1}, Values: []*Value{forged}, Written: 2}, false, false},
+		{"written", Evidence{Kind: Unacknowledged, NodeIDs: []uint32{1}, Values: []*Value{a}, Written: 3}, false, false},
+		{"unknown kind", Evidence{Kind: EvidenceKind(7), NodeIDs: []uint32{1}, Values: []*Value{a}}, false, false},
+	}
+	for _, test := range evidenceTests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.e.Verify(writers)
+			if valid, claim := err == nil, err == ErrUnverifiedClaim; valid != test.valid || claim != test.claim {
+				t.Errorf("got error %v, want valid %t and unverified claim %t", err, test.valid, test.claim)
+			}
+		})
+	}
+}
+
+func TestEvidenceLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "byzq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := signContent(t, qspec, "Winnie", "Poo", 3, 1)
+	b := signContent(t, qspec, "Winnie", "Tigger", 3, 1)
+	want := []Evidence{
+		{Kind: Equivocation, Time: time.Unix(1, 0).UTC(), NodeIDs: []uint32{1, 2}, Values: []*Value{a, b}},
+		{Kind: Unacknowledged, Time: time.Unix(2, 0).UTC(), NodeIDs: []uint32{3}, Values: []*Value{b}, Written: 2},
+	}
+
+	path := filepath.Join(dir, "evidence")
+	for _, e := range want {
+		// reopen the log to check that evidence is appended
+		l, err := OpenEvidenceLog(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Record(e); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := ReadEvidence(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d records, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Kind != w.Kind || !g.Time.Equal(w.Time) || g.Written != w.Written || len(g.Values) != len(w.Values) {
+			t.Errorf("record %d: got %v, want %v", i, &g, &w)
+			continue
+		}
+		for j := range w.Values {
+			if g.NodeIDs[j] != w.NodeIDs[j] || !g.Values[j].Equal(w.Values[j]) {
+				t.Errorf("record %d: got value %v from node %d, want %v from node %d", i, g.Values[j], g.NodeIDs[j], w.Values[j], w.NodeIDs[j])
+			}
+		}
+	}
+}
+
+func TestEvidenceCluster(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	evidence := NewEvidenceLog()
+	s := NewSuspicions(0)
+	s.SetEvidenceLog(evidence)
+	stores := []Store{NewMemStore(), NewMemStore(), NewMemStore(), NewMemStore()}
+	// The late server 1 ensures that a quorum is only found after the
+	// replies of servers 0 and 2 are received.
+	opts := func(i int) []ServerOption {
+		opts := []ServerOption{WithStore(stores[i])}
+		if i == 1 {
+			opts = append(opts, WithFaults(Late), WithFaultDelay(100*time.Millisecond))
+		}
+		return opts
+	}
+	config, stop := startServers(t, 4, s.QuorumSpec(qspec), opts)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := config.WriteNext(ctx, 1, "Winnie", "Poo"); err != nil {
+		t.Fatal(err)
+	}
+	// Servers 0 and 2 store different values signed by the writer with a
+	// timestamp that was never written.
+	stores[0].PutIfNewer(signContent(t, qspec, "Winnie", "Tigger", 9, 1))
+	stores[2].PutIfNewer(signContent(t, qspec, "Winnie", "Eeyore", 9, 1))
+	if _, err := config.ReadRegister(ctx, &Key{Key: "Winnie"}); err == nil {
+		t.Fatal("got no error, want equivocation")
+	} else if _, ok := err.(*EquivocationError); !ok {
+		t.Fatal(err)
+	}
+
+	kinds := make(map[EvidenceKind]int)
+	for _, e := range evidence.Evidence() {
+		kinds[e.Kind]++
+		if err := e.Verify(testWriters(qspec.verifier)); err != nil && err != ErrUnverifiedClaim {
+			t.Errorf("got invalid evidence %v: %v", &e, err)
+		}
+	}
+	if kinds[Unacknowledged] != 2 || kinds[Equivocation] != 1 {
+		t.Errorf("got evidence %v, want 2 unacknowledged and 1 equivocation", kinds)
+	}
+}
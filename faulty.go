@@ -0,0 +1,175 @@
+package byzq
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/peer"
+)
+
+// Fault is a Byzantine behavior of a faulty replica. Faulty replicas are
+// used to test that clients tolerate up to f misbehaving replicas.
+type Fault int
+
+const (
+	// Stale replicas reply to reads with the first value written to the key,
+	// ignoring later writes.
+	Stale Fault = iota
+	// BitFlip replicas flip a bit in the signature of their replies.
+	BitFlip
+	// ForgeTimestamp replicas reply with the highest possible timestamp
+	// without changing the signature.
+	ForgeTimestamp
+	// DropWrites replicas acknowledge writes without storing them.
+	DropWrites
+	// WrongAck replicas acknowledge writes with a timestamp different from
+	// the timestamp of the write.
+	WrongAck
+	// Late replicas delay their replies.
+	Late
+	// Equivocate replicas reply with the latest value to some clients and
+	// with the first value written to the key to others. A client is
+	// identified by the address of its connection.
+	Equivocate
+)
+
+var faultNames = []string{"stale", "bitflip", "forge-ts", "dropwrites", "wrongack", "late", "equivocate"}
+
+func (f Fault) String() string {
+	if f < 0 || int(f) >= len(faultNames) {
+		return "unknown"
+	}
+	return faultNames[f]
+}
+
+// ParseFault returns the fault with the given name, e.g. "bitflip".
+// The name is case insensitive.
+func ParseFault(name string) (Fault, error) {
+	for i, n := range faultNames {
+		if strings.EqualFold(n, name) {
+			return Fault(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown fault: %s", name)
+}
+
+// defaultFaultDelay is the delay of the replies of Late replicas.
+const defaultFaultDelay = time.Second
+
+// faultyStorageServer wraps a correct StorageServer and alters its
+// behavior according to the selected faults.
+type faultyStorageServer struct {
+	StorageServer
+	faults map[Fault]bool
+	delay  time.Duration
+
+	mu    sync.Mutex
+	first map[string]*Value // first value stored for each key
+}
+
+// NewFaultyStorageServer returns a StorageServer that misbehaves according to
+// faults, using srv to store values. Replies of Late replicas are delayed by
+// delay. The returned server must only be used for testing.
+func NewFaultyStorageServer(srv StorageServer, delay time.Duration, faults ...Fault) StorageServer {
+	s := &faultyStorageServer{
+		StorageServer: srv,
+		faults:        make(map[Fault]bool),
+		delay:         delay,
+		first:         make(map[string]*Value),
+	}
+	for _, f := range faults {
+		s.faults[f] = true
+	}
+	return s
+}
+
+func (s *faultyStorageServer) Read(ctx context.Context, k *Key) (*Value, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	v, err := s.StorageServer.Read(ctx, k)
+	if err != nil {
+		return nil, err
+	}
+	if s.faults[Stale] || (s.faults[Equivocate] && oddClient(ctx)) {
+		v = s.firstValue(k.Key)
+	}
+	if s.faults[ForgeTimestamp] && v.GetC() != nil {
+		c := *v.C
+		c.Timestamp = math.MaxInt64
+		v = &Value{C: &c, Algorithm: v.Algorithm, Signature: v.Signature}
+	}
+	if s.faults[BitFlip] && len(v.GetSignature()) > 0 {
+		sig := append([]byte(nil), v.Signature...)
+		sig[len(sig)/2] ^= 0x01
+		v = &Value{C: v.C, Algorithm: v.Algorithm, Signature: sig}
+	}
+	return v, nil
+}
+
+func (s *faultyStorageServer) ReadTimestamp(ctx context.Context, k *Key) (*Value, error) {
+	return s.Read(ctx, k)
+}
+
+func (s *faultyStorageServer) Write(ctx context.Context, v *Value) (*WriteResponse, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	if s.faults[DropWrites] {
+		return &WriteResponse{Timestamp: v.GetC().GetTimestamp(), WriterID: v.GetC().GetWriterID()}, nil
+	}
+	wr, err := s.StorageServer.Write(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if _, found := s.first[v.C.Key]; !found {
+		s.first[v.C.Key] = v
+	}
+	s.mu.Unlock()
+	if s.faults[WrongAck] {
+		wr = &WriteResponse{Timestamp: wr.Timestamp + 1, WriterID: wr.WriterID}
+	}
+	return wr, nil
+}
+
+// wait delays Late replicas until the delay has passed or ctx is done.
+func (s *faultyStorageServer) wait(ctx context.Context) error {
+	if !s.faults[Late] {
+		return nil
+	}
+	t := time.NewTimer(s.delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// firstValue returns the first value stored for key, or an empty value.
+func (s *faultyStorageServer) firstValue(key string) *Value {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, found := s.first[key]; found {
+		return v
+	}
+	return &Value{}
+}
+
+// oddClient splits clients into two groups by the hash of their address.
+func oddClient(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(p.Addr.String()))
+	return h.Sum32()%2 == 1
+}
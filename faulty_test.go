@@ -0,0 +1,147 @@
+package byzq
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestParseFault(t *testing.T) {
+	for f := Stale; f <= Equivocate; f++ {
+		got, err := ParseFault(f.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != f {
+			t.Errorf("got %v, want %v", got, f)
+		}
+	}
+	if _, err := ParseFault("crash"); err == nil {
+		t.Error("got nil error for unknown fault")
+	}
+}
+
+func TestFaultyStorageServer(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed1, err := qspec.Sign(myVal.C)
+	if err != nil {
+		t.Fatal("Failed to sign message")
+	}
+	signed2, err := qspec.Sign(myVal2.C)
+	if err != nil {
+		t.Fatal("Failed to sign message")
+	}
+	ctx := context.Background()
+	key := &Key{Key: "Winnie"}
+
+	faultTests := []struct {
+		fault Fault
+		check func(t *testing.T, wr *WriteResponse, v *Value)
+	}{
+		{Stale, func(t *testing.T, wr *WriteResponse, v *Value) {
+			if !v.Equal(signed1) {
+				t.Errorf("got %v, want stale value %v", v, signed1)
+			}
+		}},
+		{BitFlip, func(t *testing.T, wr *WriteResponse, v *Value) {
+			if !v.C.Equal(signed2.C) || qspec.verify(v) {
+				t.Errorf("got %v, want content %v with invalid signature", v, signed2.C)
+			}
+		}},
+		{ForgeTimestamp, func(t *testing.T, wr *WriteResponse, v *Value) {
+			if v.C.Timestamp != math.MaxInt64 || qspec.verify(v) {
+				t.Errorf("got %v, want forged timestamp with invalid signature", v)
+			}
+		}},
+		{DropWrites, func(t *testing.T, wr *WriteResponse, v *Value) {
+			if wr.Timestamp != signed2.C.Timestamp {
+				t.Errorf("got ack timestamp %d, want %d", wr.Timestamp, signed2.C.Timestamp)
+			}
+			if v.GetC() != nil {
+				t.Errorf("got %v, want empty value", v)
+			}
+		}},
+		{WrongAck, func(t *testing.T, wr *WriteResponse, v *Value) {
+			if wr.Timestamp == signed2.C.Timestamp {
+				t.Errorf("got ack timestamp %d, want wrong timestamp", wr.Timestamp)
+			}
+			if !v.Equal(signed2) {
+				t.Errorf("got %v, want %v", v, signed2)
+			}
+		}},
+	}
+	for _, test := range faultTests {
+		t.Run(test.fault.String(), func(t *testing.T) {
+			srv := NewFaultyStorageServer(NewStorageServer(NewMemStore(), testWriters(qspec.verifier)), 0, test.fault)
+			if _, err := srv.Write(ctx, signed1); err != nil {
+				t.Fatal(err)
+			}
+			wr, err := srv.Write(ctx, signed2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := srv.Read(ctx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.check(t, wr, v)
+		})
+	}
+
+	t.Run("late", func(t *testing.T) {
+		srv := NewFaultyStorageServer(NewStorageServer(NewMemStore(), testWriters(qspec.verifier)), time.Hour, Late)
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		if _, err := srv.Read(ctx, key); err != context.DeadlineExceeded {
+			t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+// verifyingQSpec is an AuthDataQ whose read quorum function verifies replies.
+type verifyingQSpec struct {
+	*AuthDataQ
+}
+
+func (vq verifyingQSpec) ReadQF(replies []*Value) (*Content, bool, error) {
+	return vq.SequentialVerifyReadQF(replies)
+}
+
+func TestFaultyCluster(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for f := Stale; f <= Equivocate; f++ {
+		t.Run(fmt.Sprintf("one %v replica", f), func(t *testing.T) {
+			config, stop := startServers(t, 4, verifyingQSpec{qspec}, func(i int) []ServerOption {
+				if i != 0 {
+					return nil
+				}
+				return []ServerOption{WithFaults(f), WithFaultDelay(50 * time.Millisecond)}
+			})
+			defer stop()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			for _, value := range []string{"Poo", "Tigger"} {
+				if _, err := config.WriteNext(ctx, 1, "Winnie", value); err != nil {
+					t.Fatal(err)
+				}
+			}
+			c, err := config.Read(ctx, &Key{Key: "Winnie"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.GetValue() != "Tigger" || c.GetTimestamp() != 2 {
+				t.Errorf("got %v, want value %q with timestamp 2", c, "Tigger")
+			}
+		})
+	}
+}
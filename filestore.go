@@ -0,0 +1,314 @@
+package byzq
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	logFile      = "values.log"
+	snapshotFile = "values.snapshot"
+
+	// defaultCompactAfter is the number of log records after which the log
+	// is compacted into a snapshot.
+	defaultCompactAfter = 1024
+
+	// headerSize is the size of a record header: payload length and checksum.
+	headerSize = 8
+
+	// maxRecordSize is the maximum payload length of a record. It is the
+	// default maximum size of a gRPC message, which bounds the size of the
+	// values received by a replica.
+	maxRecordSize = 4 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// FileStore is a crash-safe Store backed by files in a directory.
+// Every stored value is appended to a write-ahead log and synced to disk
+// before PutIfNewer returns. The log is periodically compacted into a
+// snapshot holding the latest value of each key. When the store is opened,
+// the snapshot is loaded and the log is replayed on top of it to recover the
+// state. The latest values are also kept in memory to serve reads.
+//
+// Each record is encoded as a 4-byte payload length, a 4-byte CRC-32C
+// checksum of the payload and the marshaled Value. A record that was only
+// partially written when the process crashed is detected by its length or
+// checksum and discarded during recovery. Since records are only appended,
+// only the last record of the log can be partially written; a corrupt record
+// followed by more records makes recovery fail instead.
+type FileStore struct {
+	mu           sync.RWMutex
+	state        map[string]*Value
+	dir          string
+	f            *os.File
+	size         int64 // size of the valid log prefix
+	records      int   // number of records in the log
+	compactAfter int
+}
+
+// OpenFileStore opens the store in dir, creating dir if needed, and recovers
+// the state from the snapshot and the log in dir.
+func OpenFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	state := make(map[string]*Value)
+
+	snap, err := os.Open(filepath.Join(dir, snapshotFile))
+	switch {
+	case err == nil:
+		fi, err := snap.Stat()
+		if err != nil {
+			snap.Close()
+			return nil, err
+		}
+		valid, _, err := readRecords(bufio.NewReader(snap), fi.Size(), state)
+		snap.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot: %v", err)
+		}
+		if valid != fi.Size() {
+			// snapshots are written atomically; a damaged one cannot be trusted
+			return nil, fmt.Errorf("corrupt snapshot %s at offset %d", snap.Name(), valid)
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, logFile), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	valid, records, err := readRecords(bufio.NewReader(f), fi.Size(), state)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to replay log: %v", err)
+	}
+	// discard any partially written record at the tail of the log
+	if err = f.Truncate(valid); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err = f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &FileStore{
+		state:        state,
+		dir:          dir,
+		f:            f,
+		size:         valid,
+		records:      records,
+		compactAfter: defaultCompactAfter,
+	}, nil
+}
+
+// Get returns the value stored for key, or nil if there is none.
+func (s *FileStore) Get(key string) (*Value, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state[key], nil
+}
+
+// PutIfNewer stores v if it is newer than the value stored for its key.
+// The value is durable when PutIfNewer returns true.
+func (s *FileStore) PutIfNewer(v *Value) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !v.GetC().Newer(s.state[v.GetC().GetKey()].GetC()) {
+		return false, nil
+	}
+	if err := s.append(v); err != nil {
+		return false, err
+	}
+	s.state[v.C.Key] = v
+	if s.records >= s.compactAfter {
+		if err := s.compact(); err != nil {
+			// the value is already durable in the log; compaction is retried later
+			log.Printf("failed to compact log in %s: %v", s.dir, err)
+		}
+	}
+	return true, nil
+}
+
+// Iterate calls fn for each stored value until fn returns an error.
+// The store must not be modified by fn.
+func (s *FileStore) Iterate(fn func(v *Value) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, v := range s.state {
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the log file.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+// readRecords applies the records read from r, which holds size bytes, to
+// state, keeping the newest value of each key. It returns the length of the
+// valid prefix of r and the number of records in it. Reading stops at an
+// incomplete or corrupt last record. A record whose length exceeds
+// maxRecordSize, or a corrupt record that is not the last one, is returned
+// as an error.
+func readRecords(r io.Reader, size int64, state map[string]*Value) (valid int64, records int, err error) {
+	var hdr [headerSize]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return valid, records, nil
+			}
+			return valid, records, err
+		}
+		length := binary.BigEndian.Uint32(hdr[0:4])
+		sum := binary.BigEndian.Uint32(hdr[4:8])
+		if length > maxRecordSize {
+			// no record of this length was ever written, so the length is
+			// corrupt rather than the record incomplete
+			return valid, records, fmt.Errorf("corrupt record at offset %d: length %d exceeds %d bytes", valid, length, maxRecordSize)
+		}
+		end := valid + headerSize + int64(length)
+		if end > size {
+			// the last record is incomplete
+			return valid, records, nil
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return valid, records, nil
+			}
+			return valid, records, err
+		}
+		v := new(Value)
+		if crc32.Checksum(payload, crcTable) != sum || v.Unmarshal(payload) != nil || v.C == nil {
+			if end == size {
+				// the last record is partially written
+				return valid, records, nil
+			}
+			return valid, records, fmt.Errorf("corrupt record at offset %d followed by %d bytes", valid, size-end)
+		}
+		if v.C.Newer(state[v.C.Key].GetC()) {
+			state[v.C.Key] = v
+		}
+		valid += int64(headerSize + len(payload))
+		records++
+	}
+}
+
+// encodeRecord returns the log record for v.
+func encodeRecord(v *Value) ([]byte, error) {
+	payload, err := v.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > maxRecordSize {
+		return nil, fmt.Errorf("value of %d bytes exceeds %d bytes", len(payload), maxRecordSize)
+	}
+	rec := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(rec[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(rec[4:8], crc32.Checksum(payload, crcTable))
+	copy(rec[headerSize:], payload)
+	return rec, nil
+}
+
+// append writes v to the log and syncs it to disk.
+func (s *FileStore) append(v *Value) error {
+	rec, err := encodeRecord(v)
+	if err != nil {
+		return err
+	}
+	if _, err = s.f.Write(rec); err == nil {
+		err = s.f.Sync()
+	}
+	if err != nil {
+		// drop the partial record so that later records remain readable
+		s.f.Truncate(s.size)
+		s.f.Seek(s.size, io.SeekStart)
+		return err
+	}
+	s.size += int64(len(rec))
+	s.records++
+	return nil
+}
+
+// compact writes the state to a new snapshot and truncates the log.
+// If the process crashes after the snapshot has been written but before the
+// log has been truncated, the log is replayed on top of the snapshot,
+// which is harmless since only newer values are kept.
+func (s *FileStore) compact() error {
+	tmp := filepath.Join(s.dir, snapshotFile+".tmp")
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, v := range s.state {
+		rec, err := encodeRecord(v)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if _, err = w.Write(rec); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err = w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, filepath.Join(s.dir, snapshotFile)); err != nil {
+		return err
+	}
+	if err = syncDir(s.dir); err != nil {
+		return err
+	}
+
+	if err = s.f.Truncate(0); err != nil {
+		return err
+	}
+	if _, err = s.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err = s.f.Sync(); err != nil {
+		return err
+	}
+	s.size, s.records = 0, 0
+	return nil
+}
+
+// syncDir syncs the directory dir, making a preceding rename durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
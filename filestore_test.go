@@ -0,0 +1,220 @@
+package byzq
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "byzq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+var pigletVal = &Value{C: &Content{Key: "Piglet", Value: "Heffalump", Timestamp: 1}}
+
+func TestFileStoreTornWrite(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	s, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.PutIfNewer(myVal); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.PutIfNewer(myVal3); err != nil {
+		t.Fatal(err)
+	}
+	size := s.size
+	s.Close()
+
+	// simulate a crash in the middle of writing the last record
+	logPath := filepath.Join(dir, logFile)
+	if err = os.Truncate(logPath, size-3); err != nil {
+		t.Fatal(err)
+	}
+	s, err = OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := s.Get("Winnie"); !v.Equal(myVal) {
+		t.Errorf("got %v after torn write, want %v", v, myVal)
+	}
+	// the torn record must be discarded so that new records can be read back
+	if _, err = s.PutIfNewer(pigletVal); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	s, err = OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if v, _ := s.Get("Piglet"); !v.Equal(pigletVal) {
+		t.Errorf("got %v, want %v", v, pigletVal)
+	}
+}
+
+// writeTestLog writes a log holding a record of myVal and myVal3 to dir, and
+// returns the path of the log and its contents.
+func writeTestLog(t *testing.T, dir string) (string, []byte) {
+	s, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []*Value{myVal, myVal3} {
+		if _, err = s.PutIfNewer(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Close()
+	logPath := filepath.Join(dir, logFile)
+	b, err := ioutil.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return logPath, b
+}
+
+func TestFileStoreRecordSize(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	logPath, b := writeTestLog(t, dir)
+	first := headerSize + int(binary.BigEndian.Uint32(b[0:4]))
+
+	// a length beyond the end of the log is an incomplete last record, and
+	// must not be allocated
+	last := append([]byte(nil), b...)
+	binary.BigEndian.PutUint32(last[first:], maxRecordSize)
+	if err := ioutil.WriteFile(logPath, last, 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := s.Get("Winnie"); !v.Equal(myVal) {
+		t.Errorf("got %v after incomplete record, want %v", v, myVal)
+	}
+	if s.size != int64(first) {
+		t.Errorf("got log of %d bytes, want %d", s.size, first)
+	}
+	s.Close()
+
+	// a length beyond the maximum record size is corrupt, even if it reaches
+	// beyond the end of the log, and the records after it must not be
+	// truncated
+	binary.BigEndian.PutUint32(b[0:], 1<<31)
+	if err = ioutil.WriteFile(logPath, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = OpenFileStore(dir); err == nil {
+		t.Error("got nil error when opening store with corrupt record length")
+	}
+	got, err := ioutil.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(b) {
+		t.Errorf("got log of %d bytes after failed recovery, want %d", len(got), len(b))
+	}
+
+	// a length beyond the maximum record size within the log is corrupt
+	b = make([]byte, headerSize+maxRecordSize+1)
+	binary.BigEndian.PutUint32(b, maxRecordSize+1)
+	if err = ioutil.WriteFile(logPath, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = OpenFileStore(dir); err == nil {
+		t.Error("got nil error when opening store with oversized record")
+	}
+}
+
+func TestFileStoreCorruptRecord(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	logPath, b := writeTestLog(t, dir)
+
+	// a checksum mismatch in the last record is a partially written record
+	last := append([]byte(nil), b...)
+	last[len(last)-1] ^= 0xff
+	if err := ioutil.WriteFile(logPath, last, 0600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := s.Get("Winnie"); !v.Equal(myVal) {
+		t.Errorf("got %v after partially written record, want %v", v, myVal)
+	}
+	s.Close()
+
+	// a checksum mismatch followed by more records is corruption, and the
+	// records after it must not be truncated
+	b[headerSize] ^= 0xff
+	if err = ioutil.WriteFile(logPath, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = OpenFileStore(dir); err == nil {
+		t.Error("got nil error when opening store with corrupt record")
+	}
+	got, err := ioutil.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(b) {
+		t.Errorf("got log of %d bytes after failed recovery, want %d", len(got), len(b))
+	}
+}
+
+func TestFileStoreCompaction(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	s, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.compactAfter = 2
+	for _, v := range []*Value{myVal, myVal3, pigletVal} {
+		if _, err = s.PutIfNewer(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.records != 1 {
+		t.Errorf("got %d records in log after compaction, want 1", s.records)
+	}
+	s.Close()
+
+	s, err = OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if v, _ := s.Get("Winnie"); !v.Equal(myVal3) {
+		t.Errorf("got %v after recovery from snapshot, want %v", v, myVal3)
+	}
+	if v, _ := s.Get("Piglet"); !v.Equal(pigletVal) {
+		t.Errorf("got %v after recovery from snapshot, want %v", v, pigletVal)
+	}
+
+	// a damaged snapshot must not be silently ignored
+	snapPath := filepath.Join(dir, snapshotFile)
+	b, err := ioutil.ReadFile(snapPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[len(b)-1] ^= 0xff
+	if err = ioutil.WriteFile(snapPath, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = OpenFileStore(dir); err == nil {
+		t.Error("got nil error when opening store with corrupt snapshot")
+	}
+}
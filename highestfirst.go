@@ -0,0 +1,161 @@
+package byzq
+
+import "sort"
+
+// VerifyHighestFirst verifies replies lazily in order of decreasing content,
+// and stops verifying as soon as the highest valid reply is found and enough
+// replies are valid for a quorum. Replies whose content is byte-equal to the
+// content of a verified reply are valid without verifying their signatures,
+// so that the common case of a quorum of replies with the same latest value
+// costs a single verification. If the signature of the highest candidate is
+// invalid, the next candidate is verified. Replies with the same write as the
+// highest valid reply are always verified to detect equivocation.
+//
+// Since replies lower than the highest valid reply are only verified until a
+// quorum is found, the Invalid count of the result may miss invalid replies.
+var VerifyHighestFirst ReadStrategy = verifyHighestFirst{}
+
+type verifyHighestFirst struct{}
+
+func (verifyHighestFirst) String() string { return "highest-first" }
+
+func (s verifyHighestFirst) Evaluate(aq *AuthDataQ, replies []*Value) ReadResult {
+	if len(replies) <= aq.q {
+		// not enough replies yet; need at least bq.q=(n+2f)/2 replies
+		return ReadResult{Replies: len(replies)}
+	}
+	e := s.newEvaluator(aq).(*highestFirstEvaluator)
+	e.replies = replies
+	return e.evaluate()
+}
+
+func (verifyHighestFirst) newEvaluator(aq *AuthDataQ) ReadEvaluator {
+	return &highestFirstEvaluator{
+		aq:       aq,
+		verified: make(map[string]*Value),
+		failed:   make(map[signature]bool),
+	}
+}
+
+// highestFirstEvaluator remembers the results of its verifications, so that
+// each signature is verified at most once by the quorum call.
+type highestFirstEvaluator struct {
+	aq       *AuthDataQ
+	replies  []*Value
+	verified map[string]*Value  // signed message -> reply with valid signature
+	failed   map[signature]bool // signatures found to be invalid
+}
+
+// signature identifies a signature of a signed message.
+type signature struct {
+	msg, sig  string
+	algorithm Algorithm
+}
+
+// candidate is the replies with byte-equal content.
+type candidate struct {
+	msg     string
+	c       *Content
+	replies []*Value
+}
+
+func (e *highestFirstEvaluator) Add(reply *Value) ReadResult {
+	e.replies = append(e.replies, reply)
+	if len(e.replies) <= e.aq.q {
+		// not enough replies yet; need at least bq.q=(n+2f)/2 replies
+		return ReadResult{Replies: len(e.replies)}
+	}
+	return e.evaluate()
+}
+
+func (e *highestFirstEvaluator) evaluate() ReadResult {
+	r := ReadResult{Replies: len(e.replies)}
+	var (
+		valid      int // replies that are empty or have verified content
+		remaining  int // replies with content of candidates not yet verified
+		candidates []*candidate
+		byMsg      = make(map[string]*candidate)
+	)
+	for _, reply := range e.replies {
+		switch {
+		case reply == nil:
+			r.Invalid++
+			continue
+		case reply.C == nil:
+			valid++
+			continue
+		}
+		b, err := reply.C.Marshal()
+		if err != nil {
+			r.Invalid++
+			continue
+		}
+		msg := string(b)
+		cand, found := byMsg[msg]
+		if !found {
+			cand = &candidate{msg: msg, c: reply.C}
+			byMsg[msg] = cand
+			candidates = append(candidates, cand)
+		}
+		cand.replies = append(cand.replies, reply)
+		remaining++
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].c.Newer(candidates[j].c)
+	})
+
+	var (
+		h       highestValue
+		highest *Content
+	)
+	for _, cand := range candidates {
+		if valid+remaining <= e.aq.q {
+			// a quorum is no longer possible
+			break
+		}
+		if highest != nil && valid > e.aq.q && !sameWrite(cand.c, highest) {
+			// the highest valid reply is found, and no other candidate can
+			// change the result
+			break
+		}
+		remaining -= len(cand.replies)
+		v := e.verify(cand)
+		if v == nil {
+			r.Invalid += len(cand.replies)
+			continue
+		}
+		valid += len(cand.replies)
+		h.add(v, e.aq.tieBreak)
+		if highest == nil {
+			highest = v.C
+		}
+	}
+	if valid <= e.aq.q {
+		// not enough valid replies yet
+		return r
+	}
+	r.Quorum = true
+	r.Value, r.Err = h.result()
+	return r
+}
+
+// verify returns a reply of cand with a valid signature, or nil if there is
+// none. Since the replies of cand have byte-equal content, at most one valid
+// signature is verified.
+func (e *highestFirstEvaluator) verify(cand *candidate) *Value {
+	if v, found := e.verified[cand.msg]; found {
+		return v
+	}
+	for _, reply := range cand.replies {
+		key := signature{cand.msg, string(reply.Signature), reply.Algorithm}
+		if e.failed[key] {
+			continue
+		}
+		if e.aq.verify(reply) {
+			e.verified[cand.msg] = reply
+			return reply
+		}
+		e.failed[key] = true
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package byzq
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestVerifyHighestFirst(t *testing.T) {
+	signer, err := NewSigner(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := NewVerifier(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter := &countingVerifier{Verifier: verifier}
+	qspec, err := NewAuthDataQFromScheme(13, signer, counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1 := signContent(t, qspec, "Winnie", "Poo", 1, 0)
+	v2 := signContent(t, qspec, "Winnie", "Poop", 2, 0)
+	forged := &Value{C: &Content{Key: "Winnie", Value: "Eeyore", Timestamp: 9}, Algorithm: v1.Algorithm, Signature: v1.Signature}
+
+	replicate := func(v *Value, n int) []*Value {
+		replies := make([]*Value, n)
+		for i := range replies {
+			replies[i] = v
+		}
+		return replies
+	}
+	highestFirstTests := []struct {
+		name     string
+		replies  []*Value
+		expected *Content
+		verified uint64 // number of verifications
+	}{
+		{"equal replies", replicate(v1, 13), v1.C, 1},
+		{"highest", append(replicate(v1, 10), v2, v2, v2), v2.C, 2},
+		{"forged highest", append(replicate(forged, 3), replicate(v1, 10)...), v1.C, 2},
+		{"distinct forged signatures", []*Value{
+			forged, {C: forged.C, Algorithm: v2.Algorithm, Signature: v2.Signature},
+			v1, v1, v1, v1, v1, v1, v1, v1, v1, v1, v1,
+		}, v1.C, 3},
+	}
+	for _, test := range highestFirstTests {
+		t.Run(test.name, func(t *testing.T) {
+			before := atomic.LoadUint64(&counter.n)
+			c, quorum, err := qspec.VerifyHighestFirstReadQF(test.replies)
+			if !quorum || err != nil || !c.Equal(test.expected) {
+				t.Errorf("got %v, %t, %v, want %v, true, nil", c, quorum, err, test.expected)
+			}
+			if n := atomic.LoadUint64(&counter.n) - before; n != test.verified {
+				t.Errorf("got %d verifications, want %d", n, test.verified)
+			}
+		})
+	}
+
+	// the evaluator verifies the highest candidate once for all replies
+	qspec.SetReadStrategy(VerifyHighestFirst)
+	e := qspec.NewReadEvaluator()
+	before := atomic.LoadUint64(&counter.n)
+	for _, reply := range replicate(v1, 13) {
+		if e.Add(reply).Quorum {
+			break
+		}
+	}
+	if n := atomic.LoadUint64(&counter.n) - before; n != 1 {
+		t.Errorf("got %d verifications, want 1", n)
+	}
+}
@@ -0,0 +1,304 @@
+package byzq
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds in seconds of the latency histograms.
+var latencyBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram is a snapshot of a latency histogram.
+type Histogram struct {
+	// Buckets are the upper bounds of the buckets, and Counts the number of
+	// observations less than or equal to each bound.
+	Buckets []float64
+	Counts  []uint64
+	Count   uint64
+	Sum     time.Duration
+}
+
+// Mean returns the mean of the observations, or zero if there are none.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// histogram is a cumulative latency histogram. It is not safe for
+// concurrent use.
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    time.Duration
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(latencyBuckets))}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	s := d.Seconds()
+	for i, le := range latencyBuckets {
+		if s <= le {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += d
+}
+
+func (h *histogram) snapshot() Histogram {
+	return Histogram{
+		Buckets: latencyBuckets,
+		Counts:  append([]uint64(nil), h.counts...),
+		Count:   h.count,
+		Sum:     h.sum,
+	}
+}
+
+// DefaultKeyClass returns the part of key before the first '/', or "default"
+// if the key has no '/'. It is used to group the metrics of keys without
+// creating one time series per key.
+func DefaultKeyClass(key string) string {
+	if i := strings.IndexByte(key, '/'); i > 0 {
+		return key[:i]
+	}
+	return "default"
+}
+
+// ServerMetrics collects metrics of a replica and exposes them in the
+// Prometheus text format.
+type ServerMetrics struct {
+	keyClass func(key string) string
+
+	mu          sync.Mutex
+	requests    map[requestLabels]*histogram
+	rejected    map[string]uint64
+	tlsFailures uint64
+	stored      map[string]*Value // newest value stored for each key
+	storedBytes int               // size of the values in stored
+}
+
+type requestLabels struct {
+	method   string
+	keyClass string
+}
+
+// Reasons for rejected writes.
+const (
+	rejectInvalid   = "invalid"   // missing content
+	rejectSignature = "signature" // not signed by a known writer
+	rejectStale     = "stale"     // not newer than the stored value
+)
+
+// NewServerMetrics returns metrics that group requests by the class of their
+// keys given by keyClass. If keyClass is nil, DefaultKeyClass is used.
+func NewServerMetrics(keyClass func(key string) string) *ServerMetrics {
+	if keyClass == nil {
+		keyClass = DefaultKeyClass
+	}
+	return &ServerMetrics{
+		keyClass: keyClass,
+		requests: make(map[requestLabels]*histogram),
+		rejected: make(map[string]uint64),
+		stored:   make(map[string]*Value),
+	}
+}
+
+// observe records a request for key that started at start. It is a no-op if
+// m is nil.
+func (m *ServerMetrics) observe(method, key string, start time.Time) {
+	if m == nil {
+		return
+	}
+	d := time.Since(start)
+	l := requestLabels{method, m.keyClass(key)}
+	m.mu.Lock()
+	h, found := m.requests[l]
+	if !found {
+		h = newHistogram()
+		m.requests[l] = h
+	}
+	h.observe(d)
+	m.mu.Unlock()
+}
+
+func (m *ServerMetrics) reject(reason string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.rejected[reason]++
+	m.mu.Unlock()
+}
+
+func (m *ServerMetrics) tlsFailure() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.tlsFailures++
+	m.mu.Unlock()
+}
+
+// countStore counts the values already held by store, such as the values a
+// FileStore recovered from disk. Later writes are counted as they are stored.
+// It must be called before the replica serves requests.
+func (m *ServerMetrics) countStore(store Store) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stored = make(map[string]*Value)
+	m.storedBytes = 0
+	return store.Iterate(func(v *Value) error {
+		m.storeLocked(v)
+		return nil
+	})
+}
+
+// store records that v was stored by the replica, so that the number of keys
+// and the size of the stored values are known without iterating the store.
+// It is a no-op if m is nil.
+func (m *ServerMetrics) store(v *Value) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.storeLocked(v)
+	m.mu.Unlock()
+}
+
+// storeLocked must be called with m.mu held. Since concurrent writes to a key
+// may be recorded in another order than they were stored, v only replaces a
+// recorded value that is older, as in Store.PutIfNewer.
+func (m *ServerMetrics) storeLocked(v *Value) {
+	old, found := m.stored[v.C.Key]
+	if found && !v.C.Newer(old.C) {
+		return
+	}
+	if found {
+		m.storedBytes -= old.Size()
+	}
+	m.stored[v.C.Key] = v
+	m.storedBytes += v.Size()
+}
+
+// WriteTo writes the metrics to w in the Prometheus text format.
+func (m *ServerMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	labels := make([]requestLabels, 0, len(m.requests))
+	for l := range m.requests {
+		labels = append(labels, l)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].method != labels[j].method {
+			return labels[i].method < labels[j].method
+		}
+		return labels[i].keyClass < labels[j].keyClass
+	})
+	requests := make([]Histogram, len(labels))
+	for i, l := range labels {
+		requests[i] = m.requests[l].snapshot()
+	}
+	rejected := make(map[string]uint64, len(m.rejected))
+	for reason, n := range m.rejected {
+		rejected[reason] = n
+	}
+	tlsFailures, keys, bytes := m.tlsFailures, len(m.stored), m.storedBytes
+	m.mu.Unlock()
+
+	p := &promWriter{w: w}
+	p.header("byzq_server_requests_total", "counter", "Number of requests handled by the replica.")
+	for i, l := range labels {
+		p.sample("byzq_server_requests_total", float64(requests[i].Count), "method", l.method, "key_class", l.keyClass)
+	}
+	p.header("byzq_server_request_duration_seconds", "histogram", "Latency of requests handled by the replica.")
+	for i, l := range labels {
+		p.histogram("byzq_server_request_duration_seconds", requests[i], "method", l.method, "key_class", l.keyClass)
+	}
+	p.header("byzq_server_rejected_writes_total", "counter", "Number of writes rejected by the replica.")
+	for _, reason := range []string{rejectInvalid, rejectSignature, rejectStale} {
+		p.sample("byzq_server_rejected_writes_total", float64(rejected[reason]), "reason", reason)
+	}
+	p.header("byzq_server_stored_keys", "gauge", "Number of keys stored by the replica.")
+	p.sample("byzq_server_stored_keys", float64(keys))
+	p.header("byzq_server_stored_bytes", "gauge", "Size of the values stored by the replica in bytes.")
+	p.sample("byzq_server_stored_bytes", float64(bytes))
+	p.header("byzq_server_tls_handshake_failures_total", "counter", "Number of failed TLS handshakes.")
+	p.sample("byzq_server_tls_handshake_failures_total", float64(tlsFailures))
+	return p.n, p.err
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (m *ServerMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.WriteTo(w)
+}
+
+// promWriter writes metrics in the Prometheus text format. The first write
+// error is kept, and later writes are skipped.
+type promWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (p *promWriter) printf(format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(p.w, format, args...)
+	p.n += int64(n)
+	p.err = err
+}
+
+func (p *promWriter) header(name, typ, help string) {
+	p.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes a sample with the given label name and value pairs.
+func (p *promWriter) sample(name string, value float64, labels ...string) {
+	p.printf("%s%s %s\n", name, formatLabels(labels), formatFloat(value))
+}
+
+func (p *promWriter) histogram(name string, h Histogram, labels ...string) {
+	for i, le := range h.Buckets {
+		p.sample(name+"_bucket", float64(h.Counts[i]), append(labels[:len(labels):len(labels)], "le", formatFloat(le))...)
+	}
+	p.sample(name+"_bucket", float64(h.Count), append(labels[:len(labels):len(labels)], "le", "+Inf")...)
+	p.sample(name+"_sum", h.Sum.Seconds(), labels...)
+	p.sample(name+"_count", float64(h.Count), labels...)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
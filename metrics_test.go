@@ -0,0 +1,185 @@
+package byzq
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestDefaultKeyClass(t *testing.T) {
+	for key, want := range map[string]string{
+		"Winnie":            "default",
+		"users/Winnie":      "users",
+		"users/Winnie/Pooh": "users",
+		"/Winnie":           "default",
+	} {
+		if got := DefaultKeyClass(key); got != want {
+			t.Errorf("DefaultKeyClass(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestServerMetrics(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := NewServerMetrics(nil)
+	srv := &storageServer{store: NewMemStore(), writers: testWriters(qspec.verifier), metrics: metrics}
+	ctx := context.Background()
+
+	signed1, err := qspec.Sign(&Content{Key: "users/Winnie", Value: "Poo", Timestamp: 1})
+	if err != nil {
+		t.Fatal("Failed to sign message")
+	}
+	signed2, err := qspec.Sign(&Content{Key: "users/Winnie", Value: "Tigger", Timestamp: 2})
+	if err != nil {
+		t.Fatal("Failed to sign message")
+	}
+	forged := &Value{C: &Content{Key: "Piglet", Timestamp: 3}, Algorithm: signed1.Algorithm, Signature: signed1.Signature}
+	for _, v := range []*Value{signed2, signed1, forged, {}} {
+		srv.Write(ctx, v)
+	}
+	srv.Read(ctx, &Key{Key: "users/Winnie"})
+	srv.ReadTimestamp(ctx, &Key{Key: "Piglet"})
+
+	var buf bytes.Buffer
+	if _, err = metrics.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`# TYPE byzq_server_requests_total counter`,
+		`byzq_server_requests_total{method="Write",key_class="users"} 2`,
+		`byzq_server_requests_total{method="Write",key_class="default"} 2`,
+		`byzq_server_requests_total{method="Read",key_class="users"} 1`,
+		`byzq_server_requests_total{method="ReadTimestamp",key_class="default"} 1`,
+		`# TYPE byzq_server_request_duration_seconds histogram`,
+		`byzq_server_request_duration_seconds_bucket{method="Read",key_class="users",le="+Inf"} 1`,
+		`byzq_server_request_duration_seconds_count{method="Write",key_class="users"} 2`,
+		`byzq_server_rejected_writes_total{reason="invalid"} 1`,
+		`byzq_server_rejected_writes_total{reason="signature"} 1`,
+		`byzq_server_rejected_writes_total{reason="stale"} 1`,
+		`byzq_server_stored_keys 1`,
+		`byzq_server_stored_bytes ` + formatFloat(float64(signed2.Size())),
+		`byzq_server_tls_handshake_failures_total 0`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("metrics do not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestServerMetricsStoredValues(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(key, value string, ts int64) *Value {
+		v, err := qspec.Sign(&Content{Key: key, Value: value, Timestamp: ts})
+		if err != nil {
+			t.Fatal("Failed to sign message")
+		}
+		return v
+	}
+	store := NewMemStore()
+	recovered := sign("Winnie", "Poo", 1)
+	if _, err := store.PutIfNewer(recovered); err != nil {
+		t.Fatal(err)
+	}
+	metrics := NewServerMetrics(nil)
+	if err := metrics.countStore(store); err != nil {
+		t.Fatal(err)
+	}
+	newer, older, other := sign("Winnie", "Tigger", 3), sign("Winnie", "Piglet", 2), sign("Piglet", "Eeyore", 1)
+
+	storedTests := []struct {
+		name  string
+		v     *Value
+		keys  int
+		bytes int
+	}{
+		{"newer", newer, 1, newer.Size()},
+		// recorded after a newer value stored concurrently
+		{"older", older, 1, newer.Size()},
+		{"other key", other, 2, newer.Size() + other.Size()},
+	}
+	for _, test := range storedTests {
+		metrics.store(test.v)
+		if keys, bytes := len(metrics.stored), metrics.storedBytes; keys != test.keys || bytes != test.bytes {
+			t.Errorf("%s: got %d keys and %d bytes, want %d keys and %d bytes", test.name, keys, bytes, test.keys, test.bytes)
+		}
+	}
+
+	// recorders are no-ops without metrics
+	var none *ServerMetrics
+	none.store(newer)
+	none.reject(rejectStale)
+	none.tlsFailure()
+}
+
+func TestServerMetricsTLSHandshakeFailures(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+
+	metrics := NewServerMetrics(nil)
+	srv, err := NewServer(
+		WithWriters(NewWriterRegistry()),
+		WithTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}}),
+		WithServerMetrics(metrics),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("not a TLS client hello"))
+	conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		metrics.mu.Lock()
+		failures := metrics.tlsFailures
+		metrics.mu.Unlock()
+		if failures == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d TLS handshake failures, want 1", failures)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
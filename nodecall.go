@@ -0,0 +1,134 @@
+package byzq
+
+import "golang.org/x/net/context"
+
+// NodeValue is a reply to a Read or ReadTimestamp quorum call and the ID of
+// the node that sent it.
+type NodeValue struct {
+	NodeID uint32
+	Value  *Value
+}
+
+// NodeWriteResponse is a reply to a Write quorum call and the ID of the node
+// that sent it.
+type NodeWriteResponse struct {
+	NodeID        uint32
+	WriteResponse *WriteResponse
+}
+
+// NodeQuorumSpec is implemented by quorum specifications whose quorum
+// functions are given the ID of the node that sent each reply. The quorum
+// calls issued by Read, ReadRegister and WriteNext use these quorum functions
+// instead of the ones of QuorumSpec and ReadQuorumSpec. Within a quorum call,
+// the last reply is the only reply not passed to earlier calls of the quorum
+// function.
+type NodeQuorumSpec interface {
+	QuorumSpec
+
+	// ReadNodeQF is the quorum function for the Read quorum call method.
+	// A non-nil error ends the quorum call with that error.
+	ReadNodeQF(replies []NodeValue) (*Content, bool, error)
+
+	// ReadTimestampNodeQF is the quorum function for the ReadTimestamp
+	// quorum call method.
+	ReadTimestampNodeQF(replies []NodeValue) (*Content, bool)
+
+	// WriteNodeQF is the quorum function for the Write quorum call method.
+	WriteNodeQF(req *Value, replies []NodeWriteResponse) (*WriteResponse, bool)
+}
+
+// nodeReadTimestamp is invoked as a ReadTimestamp quorum call on all nodes in
+// configuration c, passing the node IDs of the replies to the quorum function
+// if the quorum specification is a NodeQuorumSpec. Otherwise, the quorum
+// function of QuorumSpec is used, as by ReadTimestamp. Unlike ReadTimestamp,
+// the outcome is recorded by ClientMetrics.
+func (c *Configuration) nodeReadTimestamp(ctx context.Context, a *Key) (resp *Content, err error) {
+	nodeQSpec, byNode := c.qspec.(NodeQuorumSpec)
+	expected := c.n
+	replyChan := make(chan internalValue, expected)
+	for _, n := range c.nodes {
+		go callGRPCReadTimestamp(ctx, n, a, replyChan)
+	}
+
+	var (
+		replyValues = make([]*Value, 0, expected)
+		nodeValues  []NodeValue
+		errCount    int
+		quorum      bool
+	)
+	defer func() { c.observeCall("ReadTimestamp", len(replyValues), err) }()
+
+	for {
+		select {
+		case r := <-replyChan:
+			if r.err != nil {
+				errCount++
+				break
+			}
+			replyValues = append(replyValues, r.reply)
+			if byNode {
+				nodeValues = append(nodeValues, NodeValue{r.nid, r.reply})
+				resp, quorum = nodeQSpec.ReadTimestampNodeQF(nodeValues)
+			} else {
+				resp, quorum = c.qspec.ReadTimestampQF(replyValues)
+			}
+			if quorum {
+				return resp, nil
+			}
+		case <-ctx.Done():
+			return nil, QuorumCallError{ctx.Err().Error(), errCount, len(replyValues)}
+		}
+
+		if errCount+len(replyValues) == expected {
+			return nil, QuorumCallError{"incomplete call", errCount, len(replyValues)}
+		}
+	}
+}
+
+// nodeWrite is invoked as a Write quorum call on all nodes in configuration
+// c, passing the node IDs of the replies to the quorum function if the
+// quorum specification is a NodeQuorumSpec. Otherwise, the quorum function
+// of QuorumSpec is used, as by Write. Unlike Write, the outcome is recorded
+// by ClientMetrics.
+func (c *Configuration) nodeWrite(ctx context.Context, a *Value) (resp *WriteResponse, err error) {
+	nodeQSpec, byNode := c.qspec.(NodeQuorumSpec)
+	expected := c.n
+	replyChan := make(chan internalWriteResponse, expected)
+	for _, n := range c.nodes {
+		go callGRPCWrite(ctx, n, a, replyChan)
+	}
+
+	var (
+		replyValues = make([]*WriteResponse, 0, expected)
+		nodeValues  []NodeWriteResponse
+		errCount    int
+		quorum      bool
+	)
+	defer func() { c.observeCall("Write", len(replyValues), err) }()
+
+	for {
+		select {
+		case r := <-replyChan:
+			if r.err != nil {
+				errCount++
+				break
+			}
+			replyValues = append(replyValues, r.reply)
+			if byNode {
+				nodeValues = append(nodeValues, NodeWriteResponse{r.nid, r.reply})
+				resp, quorum = nodeQSpec.WriteNodeQF(a, nodeValues)
+			} else {
+				resp, quorum = c.qspec.WriteQF(a, replyValues)
+			}
+			if quorum {
+				return resp, nil
+			}
+		case <-ctx.Done():
+			return nil, QuorumCallError{ctx.Err().Error(), errCount, len(replyValues)}
+		}
+
+		if errCount+len(replyValues) == expected {
+			return nil, QuorumCallError{"incomplete call", errCount, len(replyValues)}
+		}
+	}
+}
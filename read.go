@@ -0,0 +1,330 @@
+package byzq
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// readRepairTimeout bounds the time spent repairing a single stale replica.
+const readRepairTimeout = 5 * time.Second
+
+// Semantics specifies the consistency guarantee provided by ReadRegister.
+type Semantics int
+
+const (
+	// Regular semantics guarantee that a read returns the value of the last
+	// completed write or of a concurrent write. Two sequential reads that are
+	// concurrent with a write may observe the new value followed by the old.
+	Regular Semantics = iota
+
+	// Atomic semantics additionally guarantee that once a read has returned
+	// a value, no later read returns an older value. This is achieved by
+	// writing the value back to a quorum before the read returns.
+	Atomic
+)
+
+func (s Semantics) String() string {
+	switch s {
+	case Regular:
+		return "regular"
+	case Atomic:
+		return "atomic"
+	}
+	return "unknown"
+}
+
+// SetSemantics sets the register semantics used by configurations
+// with this quorum specification. The default is Regular.
+func (aq *AuthDataQ) SetSemantics(s Semantics) {
+	aq.semantics = s
+}
+
+// Semantics returns the register semantics of the quorum specification.
+func (aq *AuthDataQ) Semantics() Semantics {
+	return aq.semantics
+}
+
+// SetReadRepair enables or disables read-repair for configurations with this
+// quorum specification. With read-repair enabled, ReadRegister asynchronously
+// writes the signed value it returns to every replica whose reply was older,
+// including replicas whose replies arrive after the read has returned.
+// Read-repair is redundant with Atomic semantics, since the write-back phase
+// already writes the value to all replicas.
+func (aq *AuthDataQ) SetReadRepair(enable bool) {
+	aq.readRepair = enable
+}
+
+// ReadRepairs returns the number of read-repair writes issued to stale
+// replicas by configurations with this quorum specification.
+func (aq *AuthDataQ) ReadRepairs() uint64 {
+	return atomic.LoadUint64(&aq.repairs)
+}
+
+// ReadQuorumSpec is implemented by quorum specifications with a quorum
+// function for the Read quorum call. Unlike the generated quorum calls, Read
+// is implemented by hand, so that its quorum function can end the quorum call
+// with an error, such as an EquivocationError.
+type ReadQuorumSpec interface {
+	QuorumSpec
+
+	// ReadQF is the quorum function for the Read quorum call method. A
+	// non-nil error ends the quorum call with that error.
+	ReadQF(replies []*Value) (*Content, bool, error)
+}
+
+// Read is invoked as a quorum call on all nodes in configuration c, using the
+// same argument arg, and returns the result. The configuration's quorum
+// specification must be a ReadQuorumSpec, an EvaluatorQuorumSpec or a
+// NodeQuorumSpec.
+//
+// The error distinguishes the outcomes of the read quorum function: a
+// QuorumCallError if no quorum was found before all nodes replied or ctx
+// was done, a NoValueError if a quorum was found but no reply holds a valid
+// value, and an EquivocationError if no value can be chosen.
+func (c *Configuration) Read(ctx context.Context, arg *Key) (*Content, error) {
+	resp, _, err := c.read(ctx, arg, nil)
+	return resp, err
+}
+
+// ReadRegister reads the value of arg with the register semantics selected
+// by the configuration's quorum specification. With Atomic semantics, the
+// signed value chosen by the read quorum function is written back to a quorum
+// using Write before it is returned. If read-repair is enabled, stale
+// replicas are repaired in the background after the read returns.
+// If the quorum specification is a NodeQuorumSpec, its node quorum functions
+// are used.
+func (c *Configuration) ReadRegister(ctx context.Context, arg *Key) (*Content, error) {
+	aq, ok := authDataQ(c.qspec)
+	if !ok || aq.semantics == Regular {
+		var repair *AuthDataQ
+		if ok && aq.readRepair {
+			repair = aq
+		}
+		resp, _, err := c.read(ctx, arg, repair)
+		return resp, err
+	}
+	_, v, err := c.read(ctx, arg, nil)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		// no value has been written; nothing to write back
+		return nil, nil
+	}
+	// The read quorum functions of AuthDataQ only return content of verified
+	// replies, but other node quorum functions may not; an unverified value
+	// must not be written back on behalf of its writer.
+	if !aq.verify(v) {
+		return nil, fmt.Errorf("read quorum function returned content with an invalid signature from writer %d", v.C.WriterID)
+	}
+	if _, err := c.nodeWrite(ctx, v); err != nil {
+		return nil, err
+	}
+	return v.C, nil
+}
+
+// WrapperQuorumSpec is implemented by quorum specifications that wrap another
+// quorum specification, such as those returned by Suspicions.QuorumSpec and
+// ClientMetrics.QuorumSpec. The settings of an AuthDataQ wrapped by the
+// quorum specification of a configuration, such as its register semantics,
+// apply to the configuration.
+type WrapperQuorumSpec interface {
+	QuorumSpec
+
+	// Unwrap returns the wrapped quorum specification.
+	Unwrap() QuorumSpec
+}
+
+// authDataQ returns the AuthDataQ wrapped by qspec, if any.
+func authDataQ(qspec QuorumSpec) (*AuthDataQ, bool) {
+	for {
+		switch q := qspec.(type) {
+		case *AuthDataQ:
+			return q, true
+		case WrapperQuorumSpec:
+			qspec = q.Unwrap()
+		default:
+			return nil, false
+		}
+	}
+}
+
+// read is invoked as a Read quorum call on all nodes in configuration
+// c, and returns the content selected by the read quorum function and the
+// signed reply holding it, if any. If repair is non-nil, stale replicas are
+// repaired in the background and the repairs are counted by repair. If the
+// quorum specification is a NodeQuorumSpec, the replies are passed with their
+// node IDs to its read quorum function. Otherwise, if it is an
+// EvaluatorQuorumSpec, each reply is passed once to a new evaluator.
+func (c *Configuration) read(ctx context.Context, a *Key, repair *AuthDataQ) (resp *Content, signed *Value, err error) {
+	nodeQSpec, byNode := c.qspec.(NodeQuorumSpec)
+	evalQSpec, byEvaluator := c.qspec.(EvaluatorQuorumSpec)
+	readQSpec, ok := c.qspec.(ReadQuorumSpec)
+	if !byNode && !byEvaluator && !ok {
+		return nil, nil, fmt.Errorf("quorum specification %T has no read quorum function", c.qspec)
+	}
+	var eval ReadEvaluator
+	if !byNode && byEvaluator {
+		eval = evalQSpec.NewReadEvaluator()
+	}
+
+	var ti traceInfo
+	if c.mgr != nil && c.mgr.opts.trace {
+		ti.Trace = trace.New("gorums."+c.tstring()+".Sent", "Read")
+		defer ti.Finish()
+
+		ti.firstLine.cid = c.id
+		if deadline, ok := ctx.Deadline(); ok {
+			ti.firstLine.deadline = deadline.Sub(time.Now())
+		}
+		ti.LazyLog(&ti.firstLine, false)
+		ti.LazyLog(&payload{sent: true, msg: a}, false)
+
+		defer func() {
+			ti.LazyLog(&qcresult{
+				reply: resp,
+				err:   err,
+			}, false)
+			if err != nil {
+				ti.SetError()
+			}
+		}()
+	}
+
+	expected := c.n
+	replyChan := make(chan internalValue, expected)
+	for _, n := range c.nodes {
+		go callGRPCRead(ctx, n, a, replyChan)
+	}
+
+	var (
+		replies     = make([]internalValue, 0, expected)
+		replyValues = make([]*Value, 0, expected)
+		nodeValues  []NodeValue
+		errCount    int
+		quorum      bool
+	)
+	defer func() { c.observeCall("Read", len(replyValues), err) }()
+
+	for {
+		select {
+		case r := <-replyChan:
+			if r.err != nil {
+				errCount++
+				break
+			}
+			if ti.Trace != nil {
+				ti.LazyLog(&payload{sent: false, id: r.nid, msg: r.reply}, false)
+			}
+			replies = append(replies, r)
+			replyValues = append(replyValues, r.reply)
+			var v *Value
+			switch {
+			case byNode:
+				nodeValues = append(nodeValues, NodeValue{r.nid, r.reply})
+				resp, quorum, err = nodeQSpec.ReadNodeQF(nodeValues)
+			case eval != nil:
+				result := eval.Add(r.reply)
+				v = result.Value
+				resp, quorum, err = result.QF()
+			default:
+				resp, quorum, err = readQSpec.ReadQF(replyValues)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			if quorum {
+				if v == nil {
+					v = signedValue(replyValues, resp)
+				}
+				if repair != nil && v != nil {
+					go c.readRepair(repair, v, replies, replyChan, expected-errCount-len(replies))
+				}
+				return resp, v, nil
+			}
+		case <-ctx.Done():
+			return nil, nil, QuorumCallError{ctx.Err().Error(), errCount, len(replyValues)}
+		}
+
+		if errCount+len(replyValues) == expected {
+			return nil, nil, QuorumCallError{"incomplete call", errCount, len(replyValues)}
+		}
+	}
+}
+
+func callGRPCRead(ctx context.Context, node *Node, arg *Key, replyChan chan<- internalValue) {
+	reply := new(Value)
+	start := time.Now()
+	err := grpc.Invoke(
+		ctx,
+		"/byzq.Storage/Read",
+		arg,
+		reply,
+		node.conn,
+	)
+	s, ok := status.FromError(err)
+	if ok && (s.Code() == codes.OK || s.Code() == codes.Canceled) {
+		node.setLatency(time.Since(start))
+	} else {
+		node.setLastErr(err)
+	}
+	replyChan <- internalValue{node.id, reply, err}
+}
+
+// signedValue returns the reply holding content c. The read quorum functions
+// return the content of the selected reply, so the reply is identified by
+// pointer rather than by equal content, since a faulty replica may return
+// equal content with a forged signature. The reply is only verified if the
+// quorum function verified it; callers writing it back must not assume so.
+func signedValue(replies []*Value, c *Content) *Value {
+	if c == nil {
+		return nil
+	}
+	for _, r := range replies {
+		if r != nil && r.C == c {
+			return r
+		}
+	}
+	return nil
+}
+
+// readRepair writes v to the nodes whose replies are older than v.
+// Replies still pending on replyChan are awaited, so that replicas that
+// reply after the read has returned are also repaired.
+func (c *Configuration) readRepair(aq *AuthDataQ, v *Value, replies []internalValue, replyChan <-chan internalValue, pending int) {
+	for _, r := range replies {
+		c.repairIfStale(aq, v, r)
+	}
+	for ; pending > 0; pending-- {
+		if r := <-replyChan; r.err == nil {
+			c.repairIfStale(aq, v, r)
+		}
+	}
+}
+
+func (c *Configuration) repairIfStale(aq *AuthDataQ, v *Value, r internalValue) {
+	if !v.C.Newer(r.reply.GetC()) {
+		return
+	}
+	for _, node := range c.nodes {
+		if node.id != r.nid {
+			continue
+		}
+		atomic.AddUint64(&aq.repairs, 1)
+		go func(node *Node) {
+			ctx, cancel := context.WithTimeout(context.Background(), readRepairTimeout)
+			defer cancel()
+			if _, err := node.StorageClient.Write(ctx, v); err != nil {
+				node.setLastErr(err)
+			}
+		}(node)
+		return
+	}
+}
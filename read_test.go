@@ -0,0 +1,227 @@
+package byzq
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// recordingClient is a StorageClient that reports the node ID of each Write.
+type recordingClient struct {
+	StorageClient
+	id     uint32
+	writes chan<- uint32
+}
+
+func (c *recordingClient) Write(ctx context.Context, in *Value, opts ...grpc.CallOption) (*WriteResponse, error) {
+	c.writes <- c.id
+	return &WriteResponse{Timestamp: in.C.Timestamp, WriterID: in.C.WriterID}, nil
+}
+
+func TestSignedValue(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := qspec.Sign(myVal2.C)
+	if err != nil {
+		t.Fatal("Failed to sign message")
+	}
+	// a forged reply with equal content that precedes the signed reply
+	forged := &Value{C: &Content{Key: "Winnie", Value: "Poop", Timestamp: 2}}
+	replies := []*Value{nil, forged, signed, {}, {}}
+
+	c, quorum, err := qspec.SequentialVerifyReadQF(replies[1:])
+	if !quorum || err != nil {
+		t.Fatalf("got %t, %v, want %t, nil", quorum, err, true)
+	}
+	if got := signedValue(replies, c); got != signed {
+		t.Errorf("got %v, want %v", got, signed)
+	}
+	if got := signedValue(replies, nil); got != nil {
+		t.Errorf("got %v, want nil", got)
+	}
+	if got := signedValue(replies, &Content{}); got != nil {
+		t.Errorf("got %v, want nil", got)
+	}
+}
+
+// trustingQSpec is a NodeQuorumSpec whose read quorum function returns the
+// content of the last reply of a quorum without verifying it.
+type trustingQSpec struct {
+	*AuthDataQ
+}
+
+func (q trustingQSpec) Unwrap() QuorumSpec { return q.AuthDataQ }
+
+func (q trustingQSpec) ReadNodeQF(replies []NodeValue) (*Content, bool, error) {
+	if len(replies) <= q.q {
+		return nil, false, nil
+	}
+	return replies[len(replies)-1].Value.GetC(), true, nil
+}
+
+func (q trustingQSpec) ReadTimestampNodeQF(replies []NodeValue) (*Content, bool) {
+	values := make([]*Value, len(replies))
+	for i, r := range replies {
+		values[i] = r.Value
+	}
+	return q.ReadTimestampQF(values)
+}
+
+func (q trustingQSpec) WriteNodeQF(req *Value, replies []NodeWriteResponse) (*WriteResponse, bool) {
+	responses := make([]*WriteResponse, len(replies))
+	for i, r := range replies {
+		responses[i] = r.WriteResponse
+	}
+	return q.WriteQF(req, responses)
+}
+
+func TestReadRegisterWriteBackUnverified(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qspec.SetSemantics(Atomic)
+	faulty := func(i int) []ServerOption {
+		return []ServerOption{WithFaults(BitFlip)}
+	}
+	config, stop := startServers(t, 4, trustingQSpec{qspec}, faulty)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := config.WriteNext(ctx, 1, "Winnie", "Poo"); err != nil {
+		t.Fatal(err)
+	}
+	// the replicas would reject the write-back, but the client must not
+	// send it in the first place
+	c, err := config.ReadRegister(ctx, &Key{Key: "Winnie"})
+	if _, isQC := err.(QuorumCallError); err == nil || isQC {
+		t.Errorf("got %v, %v, want error for content with an invalid signature", c, err)
+	}
+}
+
+func TestReadInvalidQuorum(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only server 0 stores the value, and it flips a bit in its signature.
+	// The others reply late, so that the invalid reply is part of the quorum
+	// of empty and invalid replies.
+	faulty := func(i int) []ServerOption {
+		if i == 0 {
+			return []ServerOption{WithFaults(BitFlip)}
+		}
+		return []ServerOption{WithFaults(DropWrites, Late), WithFaultDelay(50 * time.Millisecond)}
+	}
+	config, stop := startServers(t, 4, qspec, faulty)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := config.WriteNext(ctx, 1, "Winnie", "Poo"); err != nil {
+		t.Fatal(err)
+	}
+	c, err := config.Read(ctx, &Key{Key: "Winnie"})
+	if nv, ok := err.(*NoValueError); !ok || nv.Invalid != 1 {
+		t.Errorf("got %v, %v, want no value error with one invalid reply", c, err)
+	}
+}
+
+func TestReadEquivocation(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := []Store{NewMemStore(), NewMemStore(), NewMemStore(), NewMemStore()}
+	config, stop := startServers(t, 4, qspec, func(i int) []ServerOption {
+		return []ServerOption{WithStore(stores[i])}
+	})
+	defer stop()
+
+	// Servers 0 and 1 store a different value for the same write than
+	// servers 2 and 3, so that every quorum holds both values.
+	tigger := signContent(t, qspec, "Winnie", "Tigger", 3, 1)
+	eeyore := signContent(t, qspec, "Winnie", "Eeyore", 3, 1)
+	for i, v := range []*Value{tigger, tigger, eeyore, eeyore} {
+		stores[i].PutIfNewer(v)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := config.Read(ctx, &Key{Key: "Winnie"})
+	if ee, ok := err.(*EquivocationError); !ok || ee.Timestamp != 3 || ee.WriterID != 1 {
+		t.Errorf("got %v, %v, want equivocation error for timestamp 3 and writer 1", c, err)
+	}
+}
+
+func TestSemanticsString(t *testing.T) {
+	for s, want := range map[Semantics]string{Regular: "regular", Atomic: "atomic", Semantics(7): "unknown"} {
+		if got := s.String(); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestReadRepair(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qspec.SetReadRepair(true)
+	signed, err := qspec.Sign(myVal3.C)
+	if err != nil {
+		t.Fatal("Failed to sign message")
+	}
+
+	writes := make(chan uint32, 5)
+	c := &Configuration{n: 5, qspec: qspec}
+	for id := uint32(1); id <= 5; id++ {
+		c.nodes = append(c.nodes, &Node{id: id, StorageClient: &recordingClient{id: id, writes: writes}})
+	}
+	replies := []internalValue{
+		{nid: 1, reply: signed},
+		{nid: 2, reply: myVal},
+		{nid: 3, reply: &Value{}},
+	}
+	replyChan := make(chan internalValue, 2)
+	replyChan <- internalValue{nid: 4, reply: myVal2}
+	replyChan <- internalValue{nid: 5, reply: &Value{}, err: errors.New("unavailable")}
+
+	c.readRepair(qspec, signed, replies, replyChan, 2)
+
+	var repaired []int
+	for i := 0; i < 3; i++ {
+		select {
+		case id := <-writes:
+			repaired = append(repaired, int(id))
+		case <-time.After(time.Second):
+			t.Fatalf("got %d repairs, want 3", len(repaired))
+		}
+	}
+	sort.Ints(repaired)
+	if want := []int{2, 3, 4}; !equalInts(repaired, want) {
+		t.Errorf("got repairs to nodes %v, want %v", repaired, want)
+	}
+	if got := qspec.ReadRepairs(); got != 3 {
+		t.Errorf("got %d read repairs, want 3", got)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package byzq
+
+import "fmt"
+
+// ReadResult is the result of evaluating the replies of a Read quorum call.
+// It distinguishes between three outcomes: no quorum yet (Quorum is false),
+// a quorum with no valid value (Quorum is true and Value is nil), and a
+// quorum with a value.
+type ReadResult struct {
+	// Quorum is true if the replies constitute a Byzantine quorum.
+	Quorum bool
+	// Value is the valid reply with the highest content, or nil if there is
+	// no quorum or no reply holds a valid value.
+	Value *Value
+	// Replies is the number of replies evaluated.
+	Replies int
+	// Invalid is the number of replies whose signatures were found to be
+	// invalid. Strategies that do not verify all replies, such as
+	// VerifyHighestFirst, may not find all invalid replies.
+	Invalid int
+	// Err is non-nil if no value can be chosen among the valid replies,
+	// such as an EquivocationError.
+	Err error
+}
+
+// QF returns r as the results of a read quorum function. A quorum with no
+// valid value is returned as a NoValueError.
+func (r ReadResult) QF() (*Content, bool, error) {
+	switch {
+	case !r.Quorum:
+		return nil, false, nil
+	case r.Err != nil:
+		return nil, true, r.Err
+	case r.Value.GetC() == nil:
+		return nil, true, &NoValueError{Replies: r.Replies, Invalid: r.Invalid}
+	}
+	return r.Value.C, true, nil
+}
+
+// NoValueError is returned by Read if a quorum of replicas replied, but no
+// reply holds a valid value. If Invalid is zero, the replicas have no value
+// for the key, i.e. it has never been written.
+type NoValueError struct {
+	Replies int
+	Invalid int
+}
+
+func (e *NoValueError) Error() string {
+	return fmt.Sprintf("no valid value among %d replies (%d with invalid signatures)", e.Replies, e.Invalid)
+}
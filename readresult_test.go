@@ -0,0 +1,125 @@
+package byzq
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestReadResult(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := signContent(t, qspec, "Winnie", "Poo", 1, 0)
+	forged := &Value{C: &Content{Key: "Winnie", Value: "Tigger", Timestamp: 2}, Algorithm: signed.Algorithm, Signature: signed.Signature}
+	empty := &Value{}
+
+	readResultTests := []struct {
+		name     string
+		replies  []*Value
+		expected *Content // nil if a quorum has no valid value
+		invalid  int      // invalid replies of the NoValueError
+	}{
+		{"all invalid", []*Value{forged, forged, forged}, nil, 3},
+		{"all empty", []*Value{empty, empty, empty}, nil, 0},
+		{"empty and invalid", []*Value{empty, forged, empty, empty}, nil, 1},
+		{"value", []*Value{empty, signed, empty, forged}, signed.C, 1},
+	}
+
+	qfuncs := []struct {
+		name string
+		qf   func([]*Value) (*Content, bool, error)
+	}{
+		{"SequentialVerifyReadQF", qspec.SequentialVerifyReadQF},
+		{"ConcurrentVerifyIndexChanReadQF", qspec.ConcurrentVerifyIndexChanReadQF},
+		{"VerfiyLastReplyFirstReadQF", qspec.VerfiyLastReplyFirstReadQF},
+		{"ConcurrentVerifyWGReadQF", qspec.ConcurrentVerifyWGReadQF},
+		{"PoolVerifyReadQF", qspec.PoolVerifyReadQF},
+		{"VerifyHighestFirstReadQF", qspec.VerifyHighestFirstReadQF},
+		{"ReadQF", qspec.ReadQF},
+	}
+	for _, test := range readResultTests {
+		for _, qfunc := range qfuncs {
+			t.Run(fmt.Sprintf("%s %s", qfunc.name, test.name), func(t *testing.T) {
+				// the replies are passed as a growing slice, as done by the
+				// Read quorum call, until a quorum is found
+				replies := append([]*Value(nil), test.replies...)
+				var (
+					reply     *Content
+					byzquorum bool
+					err       error
+					n         int
+				)
+				for n < len(replies) && !byzquorum {
+					n++
+					reply, byzquorum, err = qfunc.qf(replies[:n])
+				}
+				// the strategies differ in when a quorum is found, but
+				// none may panic or return a value without a quorum
+				if !byzquorum {
+					if reply != nil || err != nil {
+						t.Errorf("got %v, %v without quorum, want nil, nil", reply, err)
+					}
+					return
+				}
+				if test.expected != nil {
+					if err != nil || !reply.Equal(test.expected) {
+						t.Errorf("got %v, %v, want %v, nil", reply, err, test.expected)
+					}
+					return
+				}
+				nv, ok := err.(*NoValueError)
+				if !ok || reply != nil {
+					t.Fatalf("got %v, %v, want no value error", reply, err)
+				}
+				if nv.Replies != n || nv.Invalid != test.invalid {
+					t.Errorf("got %d replies with %d invalid, want %d with %d invalid", nv.Replies, nv.Invalid, n, test.invalid)
+				}
+			})
+		}
+	}
+}
+
+func TestReadResultQF(t *testing.T) {
+	v := &Value{C: &Content{Key: "Winnie", Value: "Poo", Timestamp: 1}}
+	ee := &EquivocationError{Key: "Winnie", Timestamp: 1}
+
+	if c, quorum, err := (ReadResult{Replies: 2}).QF(); c != nil || quorum || err != nil {
+		t.Errorf("no quorum: got %v, %t, %v, want nil, false, nil", c, quorum, err)
+	}
+	c, quorum, err := ReadResult{Quorum: true, Replies: 3, Invalid: 1}.QF()
+	if nv, ok := err.(*NoValueError); c != nil || !quorum || !ok || nv.Replies != 3 || nv.Invalid != 1 {
+		t.Errorf("no value: got %v, %t, %v, want nil, true, no value error", c, quorum, err)
+	}
+	if c, quorum, err := (ReadResult{Quorum: true, Value: v, Replies: 3}).QF(); c != v.C || !quorum || err != nil {
+		t.Errorf("value: got %v, %t, %v, want %v, true, nil", c, quorum, err, v.C)
+	}
+	if c, quorum, err := (ReadResult{Quorum: true, Replies: 3, Err: ee}).QF(); c != nil || !quorum || err != ee {
+		t.Errorf("error: got %v, %t, %v, want nil, true, %v", c, quorum, err, ee)
+	}
+}
+
+func TestReadNoValue(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, stop := startServers(t, 4, verifyingQSpec{qspec}, nil)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := config.Read(ctx, &Key{Key: "Winnie"})
+	if nv, ok := err.(*NoValueError); !ok || nv.Invalid != 0 {
+		t.Fatalf("got %v, %v, want no value error without invalid replies", c, err)
+	}
+	if _, err := config.WriteNext(ctx, 1, "Winnie", "Poo"); err != nil {
+		t.Fatal(err)
+	}
+	if c, err = config.Read(ctx, &Key{Key: "Winnie"}); err != nil || c.GetValue() != "Poo" {
+		t.Errorf("got %v, %v, want value %q", c, err, "Poo")
+	}
+}
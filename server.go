@@ -0,0 +1,190 @@
+package byzq
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Server is a replica serving the Storage service.
+type Server struct {
+	grpcServer *grpc.Server
+	store      Store
+	logger     *log.Logger
+}
+
+// NewServer returns a replica configured by the given options, or nil and an
+// error if no writer registry is provided. Unless a store is provided, the
+// replica keeps its state in memory only.
+func NewServer(opts ...ServerOption) (*Server, error) {
+	var o serverOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.writers == nil {
+		return nil, fmt.Errorf("could not create server: no writer registry provided")
+	}
+	if o.store == nil {
+		o.store = NewMemStore()
+	}
+	grpcOpts := o.grpcServerOpts
+	if o.tlsConfig != nil {
+		creds := credentials.NewTLS(o.tlsConfig)
+		if o.metrics != nil {
+			creds = &metricsCreds{TransportCredentials: creds, metrics: o.metrics}
+		}
+		grpcOpts = append(grpcOpts, grpc.Creds(creds))
+	}
+	if o.metrics != nil {
+		if err := o.metrics.countStore(o.store); err != nil {
+			return nil, fmt.Errorf("could not create server: %v", err)
+		}
+	}
+	s := &Server{
+		grpcServer: grpc.NewServer(grpcOpts...),
+		store:      o.store,
+		logger:     o.logger,
+	}
+	var srv StorageServer = &storageServer{store: o.store, writers: o.writers, metrics: o.metrics}
+	if len(o.faults) > 0 {
+		delay := o.faultDelay
+		if delay == 0 {
+			delay = defaultFaultDelay
+		}
+		srv = NewFaultyStorageServer(srv, delay, o.faults...)
+	}
+	RegisterStorageServer(s.grpcServer, srv)
+	return s, nil
+}
+
+// Serve accepts connections on l and serves requests until the server is
+// stopped. Serve returns nil after the server has been stopped, and the error
+// that caused it to stop otherwise.
+func (s *Server) Serve(l net.Listener) error {
+	if s.logger != nil {
+		s.logger.Printf("server %s running", l.Addr())
+	}
+	return s.grpcServer.Serve(l)
+}
+
+// GracefulStop stops the server from accepting new connections and waits for
+// pending requests to finish before closing the store.
+func (s *Server) GracefulStop() {
+	s.grpcServer.GracefulStop()
+	s.closeStore()
+}
+
+// Stop closes all connections, cancelling pending requests, and closes
+// the store.
+func (s *Server) Stop() {
+	s.grpcServer.Stop()
+	s.closeStore()
+}
+
+func (s *Server) closeStore() {
+	if err := s.store.Close(); err != nil && s.logger != nil {
+		s.logger.Printf("error closing store: %v", err)
+	}
+}
+
+type serverOptions struct {
+	grpcServerOpts []grpc.ServerOption
+	tlsConfig      *tls.Config
+	store          Store
+	writers        *WriterRegistry
+	logger         *log.Logger
+	faults         []Fault
+	faultDelay     time.Duration
+	metrics        *ServerMetrics
+}
+
+// ServerOption provides a way to set different options on a new Server.
+type ServerOption func(*serverOptions)
+
+// WithGrpcServerOptions returns a ServerOption which sets any gRPC server
+// options the Server should use.
+func WithGrpcServerOptions(opts ...grpc.ServerOption) ServerOption {
+	return func(o *serverOptions) {
+		o.grpcServerOpts = opts
+	}
+}
+
+// WithTLSConfig returns a ServerOption which sets the TLS configuration used
+// to authenticate the Server to its clients. Without it, the Server accepts
+// unauthenticated connections.
+func WithTLSConfig(config *tls.Config) ServerOption {
+	return func(o *serverOptions) {
+		o.tlsConfig = config
+	}
+}
+
+// WithStore returns a ServerOption which sets the store holding the state
+// of the Server. The store is closed when the Server is stopped.
+func WithStore(store Store) ServerOption {
+	return func(o *serverOptions) {
+		o.store = store
+	}
+}
+
+// WithWriters returns a ServerOption which sets the registry of writers
+// whose writes the Server accepts. This option is required.
+func WithWriters(writers *WriterRegistry) ServerOption {
+	return func(o *serverOptions) {
+		o.writers = writers
+	}
+}
+
+// WithServerLogger returns a ServerOption which sets an optional logger for
+// the Server.
+func WithServerLogger(logger *log.Logger) ServerOption {
+	return func(o *serverOptions) {
+		o.logger = logger
+	}
+}
+
+// WithFaults returns a ServerOption which makes the Server misbehave
+// according to the given faults. This option must only be used for testing.
+func WithFaults(faults ...Fault) ServerOption {
+	return func(o *serverOptions) {
+		o.faults = faults
+	}
+}
+
+// WithFaultDelay returns a ServerOption which sets the delay of the replies
+// of a Server with the Late fault. The default is one second.
+func WithFaultDelay(d time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.faultDelay = d
+	}
+}
+
+// WithServerMetrics returns a ServerOption which sets the metrics collected
+// by the Server. A ServerMetrics can only be used by one Server.
+func WithServerMetrics(metrics *ServerMetrics) ServerOption {
+	return func(o *serverOptions) {
+		o.metrics = metrics
+	}
+}
+
+// metricsCreds counts failed TLS handshakes.
+type metricsCreds struct {
+	credentials.TransportCredentials
+	metrics *ServerMetrics
+}
+
+func (c *metricsCreds) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	conn, info, err := c.TransportCredentials.ServerHandshake(conn)
+	if err != nil {
+		c.metrics.tlsFailure()
+	}
+	return conn, info, err
+}
+
+func (c *metricsCreds) Clone() credentials.TransportCredentials {
+	return &metricsCreds{TransportCredentials: c.TransportCredentials.Clone(), metrics: c.metrics}
+}
@@ -0,0 +1,113 @@
+package byzq
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+func TestNewServerRequiresWriters(t *testing.T) {
+	if _, err := NewServer(); err == nil {
+		t.Error("got nil error when creating server without writer registry")
+	}
+}
+
+// testWriters returns a registry in which writers 0 to 2 all sign with the
+// key verified by verifier, since the tests sign the values of several
+// writers with the same key.
+func testWriters(verifier Verifier) *WriterRegistry {
+	return NewWriterRegistry(verifier, verifier, verifier)
+}
+
+// startServers starts n servers accepting writes signed by priv and returns
+// a configuration of the servers using qspec, and a function that stops the
+// servers. The options of server i are returned by opts, which may be nil.
+// The configuration's manager additionally uses dialOpts.
+func startServers(t testing.TB, n int, qspec QuorumSpec, opts func(i int) []ServerOption, dialOpts ...grpc.DialOption) (*Configuration, func()) {
+	verifier, err := NewVerifier(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writers := testWriters(verifier)
+
+	var (
+		addrs   []string
+		servers []*Server
+	)
+	stop := func() {
+		for _, srv := range servers {
+			srv.Stop()
+		}
+	}
+	for i := 0; i < n; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			stop()
+			t.Fatal(err)
+		}
+		srvOpts := []ServerOption{WithWriters(writers)}
+		if opts != nil {
+			srvOpts = append(srvOpts, opts(i)...)
+		}
+		srv, err := NewServer(srvOpts...)
+		if err != nil {
+			stop()
+			t.Fatal(err)
+		}
+		go srv.Serve(l)
+		servers = append(servers, srv)
+		addrs = append(addrs, l.Addr().String())
+	}
+
+	dialOpts = append([]grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithTimeout(time.Second),
+	}, dialOpts...)
+	mgr, err := NewManager(addrs, WithGrpcDialOptions(dialOpts...))
+	if err != nil {
+		stop()
+		t.Fatal(err)
+	}
+	config, err := mgr.NewConfiguration(mgr.NodeIDs(), qspec)
+	if err != nil {
+		mgr.Close()
+		stop()
+		t.Fatal(err)
+	}
+	return config, func() {
+		mgr.Close()
+		stop()
+	}
+}
+
+func TestServer(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, stop := startServers(t, 4, qspec, nil)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i, value := range []string{"Poo", "Tigger"} {
+		wr, err := config.WriteNext(ctx, 1, "Winnie", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := int64(i + 1); wr.Timestamp != want {
+			t.Errorf("got timestamp %d, want %d", wr.Timestamp, want)
+		}
+	}
+	c, err := config.Read(ctx, &Key{Key: "Winnie"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.GetValue() != "Tigger" {
+		t.Errorf("got %v, want value %q", c, "Tigger")
+	}
+}
@@ -0,0 +1,71 @@
+package byzq
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// storageServer implements the StorageServer interface of a replica on top
+// of a Store.
+type storageServer struct {
+	store   Store
+	writers *WriterRegistry
+	metrics *ServerMetrics // nil if metrics are not collected
+}
+
+// NewStorageServer returns a StorageServer that keeps its state in store and
+// rejects writes that are not signed by the writer whose ID they hold in
+// writers. If writers is nil, all writes are rejected. The returned server
+// can be registered with RegisterStorageServer, which allows replicas to be
+// embedded in other gRPC services with their own persistence.
+func NewStorageServer(store Store, writers *WriterRegistry) StorageServer {
+	return &storageServer{store: store, writers: writers}
+}
+
+func (s *storageServer) Read(ctx context.Context, k *Key) (*Value, error) {
+	defer s.metrics.observe("Read", k.Key, time.Now())
+	return s.read(k)
+}
+
+func (s *storageServer) ReadTimestamp(ctx context.Context, k *Key) (*Value, error) {
+	defer s.metrics.observe("ReadTimestamp", k.Key, time.Now())
+	return s.read(k)
+}
+
+func (s *storageServer) read(k *Key) (*Value, error) {
+	v, err := s.store.Get(k.Key)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to read %s: %v", k.Key, err)
+	}
+	if v == nil {
+		// no value has been written; reply with an empty value
+		return &Value{}, nil
+	}
+	return v, nil
+}
+
+func (s *storageServer) Write(ctx context.Context, v *Value) (*WriteResponse, error) {
+	defer s.metrics.observe("Write", v.GetC().GetKey(), time.Now())
+	if v.GetC() == nil {
+		s.metrics.reject(rejectInvalid)
+		return nil, status.Error(codes.InvalidArgument, "write rejected: missing content")
+	}
+	if !s.writers.Verify(v) {
+		s.metrics.reject(rejectSignature)
+		return nil, status.Errorf(codes.PermissionDenied, "write rejected: invalid signature for %v", v.C)
+	}
+	stored, err := s.store.PutIfNewer(v)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to persist write: %v", err)
+	}
+	if stored {
+		s.metrics.store(v)
+	} else {
+		// acknowledged, since the replica already holds a newer value
+		s.metrics.reject(rejectStale)
+	}
+	return &WriteResponse{Timestamp: v.C.Timestamp, WriterID: v.C.WriterID}, nil
+}
@@ -0,0 +1,91 @@
+package byzq
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStorageServer(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewStorageServer(NewMemStore(), testWriters(qspec.verifier))
+	ctx := context.Background()
+	key := &Key{Key: "Winnie"}
+
+	v, err := srv.Read(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.GetC() != nil {
+		t.Errorf("got %v before any write, want empty value", v)
+	}
+
+	signed3, err := qspec.Sign(myVal3.C)
+	if err != nil {
+		t.Fatal("Failed to sign message")
+	}
+	signed2, err := qspec.Sign(myVal2.C)
+	if err != nil {
+		t.Fatal("Failed to sign message")
+	}
+	forged := &Value{C: myVal4.C, Algorithm: signed3.Algorithm, Signature: signed3.Signature}
+	unknown := signContent(t, qspec, "Winnie", "Tigger", 4, 7)
+
+	writeTests := []struct {
+		name string
+		v    *Value
+		code codes.Code
+		want *Value // value read back after the write
+	}{
+		{"missing content", &Value{}, codes.InvalidArgument, nil},
+		{"signed", signed3, codes.OK, signed3},
+		{"older", signed2, codes.OK, signed3},
+		{"forged", forged, codes.PermissionDenied, signed3},
+		{"unknown writer", unknown, codes.PermissionDenied, signed3},
+	}
+	for _, test := range writeTests {
+		t.Run(test.name, func(t *testing.T) {
+			wr, err := srv.Write(ctx, test.v)
+			if status.Code(err) != test.code {
+				t.Fatalf("got error %v, want code %v", err, test.code)
+			}
+			if err == nil && wr.Timestamp != test.v.C.Timestamp {
+				t.Errorf("got ack timestamp %d, want %d", wr.Timestamp, test.v.C.Timestamp)
+			}
+			v, err := srv.ReadTimestamp(ctx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.want == nil {
+				test.want = &Value{}
+			}
+			if !v.Equal(test.want) {
+				t.Errorf("got %v, want %v", v, test.want)
+			}
+		})
+	}
+}
+
+func TestStorageServerNoWriters(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewStorageServer(NewMemStore(), nil)
+	ctx := context.Background()
+	if _, err := srv.Write(ctx, signContent(t, qspec, "Winnie", "Poo", 1, 0)); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("got error %v, want code %v", err, codes.PermissionDenied)
+	}
+	v, err := srv.Read(ctx, &Key{Key: "Winnie"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.GetC() != nil {
+		t.Errorf("got %v after rejected write, want empty value", v)
+	}
+}
@@ -0,0 +1,69 @@
+package byzq
+
+import "sync"
+
+// Store is the interface to the state of a replica. Implementations must be
+// safe for concurrent use. Values passed to and returned from a Store must not
+// be modified.
+type Store interface {
+	// Get returns the value stored for key, or nil if there is none.
+	Get(key string) (*Value, error)
+
+	// PutIfNewer stores v if there is no value for the key of v, or if v is
+	// newer than the stored value, and reports whether v was stored.
+	PutIfNewer(v *Value) (stored bool, err error)
+
+	// Iterate calls fn for each stored value until fn returns an error,
+	// which is then returned by Iterate.
+	Iterate(fn func(v *Value) error) error
+
+	// Close releases the resources held by the store.
+	Close() error
+}
+
+// MemStore is a Store that keeps values in memory only.
+type MemStore struct {
+	mu    sync.RWMutex
+	state map[string]*Value
+}
+
+// NewMemStore returns an empty in-memory store.
+func NewMemStore() *MemStore {
+	return &MemStore{state: make(map[string]*Value)}
+}
+
+// Get returns the value stored for key, or nil if there is none.
+func (s *MemStore) Get(key string) (*Value, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state[key], nil
+}
+
+// PutIfNewer stores v if it is newer than the value stored for its key.
+func (s *MemStore) PutIfNewer(v *Value) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !v.GetC().Newer(s.state[v.GetC().GetKey()].GetC()) {
+		return false, nil
+	}
+	s.state[v.C.Key] = v
+	return true, nil
+}
+
+// Iterate calls fn for each stored value until fn returns an error.
+// The store must not be modified by fn.
+func (s *MemStore) Iterate(fn func(v *Value) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, v := range s.state {
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close is a no-op for in-memory stores.
+func (s *MemStore) Close() error {
+	return nil
+}
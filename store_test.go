@@ -0,0 +1,103 @@
+package byzq
+
+import (
+	"os"
+	"testing"
+)
+
+var putIfNewerTests = []struct {
+	name   string
+	v      *Value
+	stored bool
+	want   *Value // value stored for the key afterwards
+}{
+	{"first write", w1Val, true, w1Val},
+	{"same write", w1Val, false, w1Val},
+	{"concurrent writer", w2Val, true, w2Val},
+	{"older writer", w1Val, false, w2Val},
+	{"newer timestamp", myVal3, true, myVal3},
+	{"older timestamp", myVal, false, myVal3},
+	{"other key", pigletVal, true, pigletVal},
+}
+
+// testStore runs the tests that every Store must pass.
+func testStore(t *testing.T, s Store) {
+	v, err := s.Get("Winnie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != nil {
+		t.Errorf("got %v from empty store, want nil", v)
+	}
+	for _, test := range putIfNewerTests {
+		stored, err := s.PutIfNewer(test.v)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if stored != test.stored {
+			t.Errorf("%s: got stored=%t, want %t", test.name, stored, test.stored)
+		}
+		got, err := s.Get(test.v.C.Key)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	keys := make(map[string]*Value)
+	err = s.Iterate(func(v *Value) error {
+		keys[v.C.Key] = v
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || !keys["Winnie"].Equal(myVal3) || !keys["Piglet"].Equal(pigletVal) {
+		t.Errorf("got %v from Iterate, want values %v and %v", keys, myVal3, pigletVal)
+	}
+	stop := os.ErrClosed
+	calls := 0
+	err = s.Iterate(func(*Value) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("got error %v after %d calls, want %v after 1 call", err, calls, stop)
+	}
+}
+
+func TestMemStore(t *testing.T) {
+	s := NewMemStore()
+	testStore(t, s)
+	if err := s.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	s, err := OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// recover the state from the log
+	s, err = OpenFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if v, _ := s.Get("Winnie"); !v.Equal(myVal3) {
+		t.Errorf("got %v after recovery, want %v", v, myVal3)
+	}
+	if v, _ := s.Get("Piglet"); !v.Equal(pigletVal) {
+		t.Errorf("got %v after recovery, want %v", v, pigletVal)
+	}
+}
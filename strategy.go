@@ -0,0 +1,184 @@
+package byzq
+
+import "sync"
+
+// ReadStrategy evaluates the replies of a Read quorum call for a quorum
+// specification. The strategies differ in how and when the signatures of the
+// replies are verified, but are interchangeable: given the same replies in
+// the same order, every strategy returns the same result. A strategy must:
+//
+//   - declare a quorum only once more than q replies are verified or empty,
+//     where an empty reply is sent by a replica that has no value for the key;
+//   - return the verified reply with the highest content, or an
+//     EquivocationError as described by SetTieBreak;
+//   - be safe to call with a growing slice of replies, as done by the Read
+//     quorum call, and from concurrent quorum calls.
+//
+// Strategies are selected with SetReadStrategy.
+type ReadStrategy interface {
+	// Evaluate evaluates replies for aq.
+	Evaluate(aq *AuthDataQ, replies []*Value) ReadResult
+	// String returns the name of the strategy.
+	String() string
+}
+
+var (
+	// SequentialVerify verifies all replies one by one.
+	SequentialVerify ReadStrategy = sequentialVerify{}
+	// ConcurrentVerifyWG verifies all replies concurrently, one goroutine
+	// per reply, and waits for all of them with a WaitGroup.
+	ConcurrentVerifyWG ReadStrategy = concurrentVerifyWG{}
+	// ConcurrentVerifyIndexChan verifies all replies concurrently, one
+	// goroutine per reply, and gives up as soon as too many replies have
+	// failed verification.
+	ConcurrentVerifyIndexChan ReadStrategy = concurrentVerifyIndexChan{}
+	// VerifyLastReplyFirst verifies only the last reply, and removes it from
+	// replies by setting it to nil if it is invalid. It relies on earlier
+	// replies having been verified by earlier calls with the same slice.
+	VerifyLastReplyFirst ReadStrategy = verifyLastReplyFirst{}
+)
+
+// SetReadStrategy selects the strategy used by ReadQF and
+// NewReadEvaluator to evaluate the replies of a Read quorum call. A nil
+// strategy selects IncrementalVerify. Like SetVerifyCache, it must only be
+// called while setting up aq, before it is used by quorum calls.
+func (aq *AuthDataQ) SetReadStrategy(s ReadStrategy) {
+	aq.strategy = s
+}
+
+func (aq *AuthDataQ) readStrategy() ReadStrategy {
+	if aq.strategy == nil {
+		return IncrementalVerify
+	}
+	return aq.strategy
+}
+
+// result returns the result for replies, of which verified are the replies
+// with valid signatures. A quorum requires more than q replies that are
+// verified or empty; replies removed by setting them to nil are invalid.
+func (aq *AuthDataQ) result(replies, verified []*Value) ReadResult {
+	r := ReadResult{Replies: len(replies), Invalid: len(replies) - len(verified)}
+	for _, reply := range replies {
+		if reply != nil && reply.C == nil {
+			r.Invalid--
+		}
+	}
+	if r.Replies-r.Invalid <= aq.q {
+		// not enough valid replies yet
+		return r
+	}
+	r.Quorum = true
+	r.Value, r.Err = aq.highest(verified)
+	return r
+}
+
+type sequentialVerify struct{}
+
+func (sequentialVerify) String() string { return "sequential" }
+
+func (sequentialVerify) Evaluate(aq *AuthDataQ, replies []*Value) ReadResult {
+	if len(replies) <= aq.q {
+		// not enough replies yet; need at least bq.q=(n+2f)/2 replies
+		return ReadResult{Replies: len(replies)}
+	}
+	verified := make([]*Value, 0, len(replies))
+	for _, reply := range replies {
+		if aq.verify(reply) {
+			verified = append(verified, reply)
+		}
+	}
+	return aq.result(replies, verified)
+}
+
+type concurrentVerifyWG struct{}
+
+func (concurrentVerifyWG) String() string { return "concurrent-wg" }
+
+func (concurrentVerifyWG) Evaluate(aq *AuthDataQ, replies []*Value) ReadResult {
+	if len(replies) <= aq.q {
+		// not enough replies yet; need at least bq.q=(n+2f)/2 replies
+		return ReadResult{Replies: len(replies)}
+	}
+	verified := make([]bool, len(replies))
+	wg := &sync.WaitGroup{}
+	for i, reply := range replies {
+		wg.Add(1)
+		go func(i int, r *Value) {
+			verified[i] = aq.verify(r)
+			wg.Done()
+		}(i, reply)
+	}
+	wg.Wait()
+	verifiedReplies := make([]*Value, 0, len(replies))
+	for i, v := range verified {
+		if v {
+			verifiedReplies = append(verifiedReplies, replies[i])
+		}
+	}
+	return aq.result(replies, verifiedReplies)
+}
+
+type concurrentVerifyIndexChan struct{}
+
+func (concurrentVerifyIndexChan) String() string { return "concurrent-indexchan" }
+
+func (concurrentVerifyIndexChan) Evaluate(aq *AuthDataQ, replies []*Value) ReadResult {
+	if len(replies) <= aq.q {
+		// not enough replies yet; need at least bq.q=(n+2f)/2 replies
+		return ReadResult{Replies: len(replies)}
+	}
+
+	veriresult := make(chan int, len(replies))
+	for i, reply := range replies {
+		go func(i int, r *Value) {
+			if r.GetC() != nil && !aq.verify(r) {
+				i = -1
+			}
+			veriresult <- i
+		}(i, reply)
+	}
+
+	cnt := 0
+	verified := make([]*Value, 0, len(replies))
+	for j := 0; j < len(replies); j++ {
+		i := <-veriresult
+		if i == -1 {
+			// some signature could not be verified:
+			cnt++
+			if len(replies)-cnt <= aq.q {
+				return ReadResult{Replies: len(replies), Invalid: cnt}
+			}
+			continue
+		}
+		if replies[i].GetC() != nil {
+			verified = append(verified, replies[i])
+		}
+	}
+	return aq.result(replies, verified)
+}
+
+type verifyLastReplyFirst struct{}
+
+func (verifyLastReplyFirst) String() string { return "verify-last-first" }
+
+func (verifyLastReplyFirst) Evaluate(aq *AuthDataQ, replies []*Value) ReadResult {
+	if len(replies) < 1 {
+		return ReadResult{}
+	}
+	if last := replies[len(replies)-1]; last.GetC() != nil && !aq.verify(last) {
+		// remove the last reply, since it failed to verify
+		replies[len(replies)-1] = nil
+	}
+	if len(replies) <= aq.q {
+		// not enough replies yet; need at least bq.q=(n+2f)/2 replies
+		return ReadResult{Replies: len(replies)}
+	}
+
+	verified := make([]*Value, 0, len(replies))
+	for _, reply := range replies {
+		if reply.GetC() != nil {
+			verified = append(verified, reply)
+		}
+	}
+	return aq.result(replies, verified)
+}
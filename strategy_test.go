@@ -0,0 +1,170 @@
+package byzq
+
+import (
+	"sync"
+	"testing"
+)
+
+// readStrategies are the strategies that must pass the conformance suite.
+var readStrategies = []ReadStrategy{
+	IncrementalVerify,
+	SequentialVerify,
+	ConcurrentVerifyWG,
+	ConcurrentVerifyIndexChan,
+	VerifyLastReplyFirst,
+	PoolVerify,
+	NewVerifyPool(1),
+	VerifyHighestFirst,
+	NewAdaptiveStrategy(),
+}
+
+func TestReadStrategies(t *testing.T) {
+	for _, s := range readStrategies {
+		t.Run(s.String(), func(t *testing.T) {
+			testReadStrategy(t, s)
+		})
+	}
+}
+
+// testReadStrategy is the conformance suite for read strategies. The replies
+// of each test are evaluated with a growing slice, as done by the Read
+// quorum call, until a quorum is found.
+func testReadStrategy(t *testing.T, s ReadStrategy) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tieBreakQSpec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tieBreakQSpec.SetTieBreak(true)
+
+	v1 := signContent(t, qspec, "Winnie", "Poo", 1, 0)
+	v2 := signContent(t, qspec, "Winnie", "Poop", 2, 0)
+	a := signContent(t, qspec, "Winnie", "Pooh", 3, 0)
+	b := signContent(t, qspec, "Winnie", "Tigger", 3, 0)
+	v4 := signContent(t, qspec, "Winnie", "Piglet", 4, 0)
+	forged := &Value{C: &Content{Key: "Winnie", Value: "Eeyore", Timestamp: 9}, Algorithm: v1.Algorithm, Signature: v1.Signature}
+	empty := &Value{}
+
+	const (
+		value = iota
+		noValue
+		equivocation
+	)
+	conformanceTests := []struct {
+		name     string
+		replies  []*Value
+		tieBreak bool
+		quorumAt int // number of replies when a quorum is found, or 0 if never
+		want     int // kind of result at quorum
+		expected *Content
+		invalid  int // invalid replies at quorum
+	}{
+		{"no replies", nil, false, 0, value, nil, 0},
+		{"below quorum", []*Value{v1, v1}, false, 0, value, nil, 0},
+		{"quorum", []*Value{v1, v1, v1, v1}, false, 3, value, v1.C, 0},
+		{"highest", []*Value{v1, v2, v1}, false, 3, value, v2.C, 0},
+		{"highest last", []*Value{v1, v1, v2}, false, 3, value, v2.C, 0},
+		{"invalid not counted", []*Value{v1, forged, v1, v1}, false, 4, value, v1.C, 1},
+		{"invalid last", []*Value{v1, v1, forged, v2}, false, 4, value, v2.C, 1},
+		{"too many invalid", []*Value{forged, v1, forged, v1}, false, 0, value, nil, 0},
+		{"all invalid", []*Value{forged, forged, forged, forged}, false, 0, value, nil, 0},
+		{"empty counted", []*Value{empty, v1, empty}, false, 3, value, v1.C, 0},
+		{"all empty", []*Value{empty, empty, empty}, false, 3, noValue, nil, 0},
+		{"empty and invalid", []*Value{empty, forged, empty, empty}, false, 4, noValue, nil, 1},
+		{"equivocation", []*Value{a, b, v1}, false, 3, equivocation, nil, 0},
+		{"newer than equivocation", []*Value{a, b, v4}, false, 3, value, v4.C, 0},
+		{"tie-break", []*Value{b, a, b}, true, 3, value, a.C, 0},
+	}
+
+	for _, test := range conformanceTests {
+		t.Run(test.name, func(t *testing.T) {
+			aq := qspec
+			if test.tieBreak {
+				aq = tieBreakQSpec
+			}
+			replies := append([]*Value(nil), test.replies...)
+			var r ReadResult
+			quorumAt := 0
+			for i := 0; i <= len(replies); i++ {
+				r = s.Evaluate(aq, replies[:i])
+				if r.Replies != i {
+					t.Errorf("got %d replies evaluated, want %d", r.Replies, i)
+				}
+				if r.Quorum {
+					quorumAt = i
+					break
+				}
+				if r.Value != nil || r.Err != nil {
+					t.Errorf("got %v, %v without quorum, want nil, nil", r.Value, r.Err)
+				}
+			}
+			if quorumAt != test.quorumAt {
+				t.Fatalf("got quorum at %d replies, want %d", quorumAt, test.quorumAt)
+			}
+			if !r.Quorum {
+				return
+			}
+			c, _, err := r.QF()
+			switch test.want {
+			case value:
+				if err != nil || !c.Equal(test.expected) {
+					t.Errorf("got %v, %v, want %v, nil", c, err, test.expected)
+				}
+			case noValue:
+				if _, ok := err.(*NoValueError); !ok {
+					t.Errorf("got %v, %v, want no value error", c, err)
+				}
+			case equivocation:
+				if _, ok := err.(*EquivocationError); !ok {
+					t.Errorf("got %v, %v, want equivocation error", c, err)
+				}
+			}
+			// lazy strategies may not find all invalid replies
+			if r.Invalid > test.invalid {
+				t.Errorf("got %d invalid replies, want at most %d", r.Invalid, test.invalid)
+			}
+		})
+	}
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				replies := []*Value{v1, forged, v2, v1}
+				var r ReadResult
+				for j := 1; j <= len(replies) && !r.Quorum; j++ {
+					r = s.Evaluate(qspec, replies[:j])
+				}
+				if c, _, err := r.QF(); err != nil || !c.Equal(v2.C) {
+					t.Errorf("got %v, %v, want %v, nil", c, err, v2.C)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func TestSetReadStrategy(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := qspec.readStrategy(); s != IncrementalVerify {
+		t.Errorf("got default strategy %v, want %v", s, IncrementalVerify)
+	}
+	v := signContent(t, qspec, "Winnie", "Poo", 1, 0)
+	for _, s := range readStrategies {
+		qspec.SetReadStrategy(s)
+		if got := qspec.readStrategy(); got != s {
+			t.Errorf("got strategy %v, want %v", got, s)
+		}
+		if c, quorum, err := qspec.ReadQF([]*Value{v, v, v}); !quorum || err != nil || !c.Equal(v.C) {
+			t.Errorf("%v: got %v, %t, %v, want %v, true, nil", s, c, quorum, err, v.C)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package byzq
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Offense is a misbehavior of a replica observed by a client.
+type Offense int
+
+const (
+	// InvalidSignature is a reply with a value whose signature is not a
+	// valid signature of the writer.
+	InvalidSignature Offense = iota
+	// Contradiction is a reply that contradicts the quorum of correct
+	// replies, such as a write acknowledgement with another timestamp than
+	// the timestamp of the write.
+	Contradiction
+)
+
+func (o Offense) String() string {
+	switch o {
+	case InvalidSignature:
+		return "invalid signature"
+	case Contradiction:
+		return "contradiction"
+	}
+	return "unknown"
+}
+
+// Suspicions records the offenses of replicas and keeps a suspicion score
+// for each node, which is the number of offenses recorded for the node.
+// Suspicions are recorded by the quorum functions of the quorum
+// specification returned by QuorumSpec, and are used by the Manager to
+// exclude or deprioritize suspected nodes in new configurations.
+type Suspicions struct {
+	threshold int
+	evidence  *EvidenceLog
+
+	mu       sync.Mutex
+	offenses map[uint32]map[Offense]uint64
+	scores   map[uint32]int
+}
+
+// NewSuspicions returns an empty record of suspicions. Nodes whose score
+// reaches threshold are suspected. A threshold of zero means that no node is
+// suspected, so that nodes are only deprioritized by their score.
+func NewSuspicions(threshold int) *Suspicions {
+	return &Suspicions{
+		threshold: threshold,
+		offenses:  make(map[uint32]map[Offense]uint64),
+		scores:    make(map[uint32]int),
+	}
+}
+
+// SetEvidenceLog sets the log to which evidence of misbehavior found by the
+// quorum functions is recorded. The default is to record no evidence.
+func (s *Suspicions) SetEvidenceLog(l *EvidenceLog) {
+	s.evidence = l
+}
+
+// Suspect records an offense of the node with the given ID.
+// It is a no-op on a nil receiver.
+func (s *Suspicions) Suspect(nodeID uint32, o Offense) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offenses, found := s.offenses[nodeID]
+	if !found {
+		offenses = make(map[Offense]uint64)
+		s.offenses[nodeID] = offenses
+	}
+	offenses[o]++
+	s.scores[nodeID]++
+}
+
+// Score returns the suspicion score of the node with the given ID.
+func (s *Suspicions) Score(nodeID uint32) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scores[nodeID]
+}
+
+// Offenses returns the number of offenses of each kind recorded for the node
+// with the given ID.
+func (s *Suspicions) Offenses(nodeID uint32) map[Offense]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offenses := make(map[Offense]uint64, len(s.offenses[nodeID]))
+	for o, n := range s.offenses[nodeID] {
+		offenses[o] = n
+	}
+	return offenses
+}
+
+// Suspected reports whether the score of the node with the given ID has
+// reached the threshold.
+func (s *Suspicions) Suspected(nodeID uint32) bool {
+	return s.threshold > 0 && s.Score(nodeID) >= s.threshold
+}
+
+// Forgive clears the offenses of the node with the given ID, e.g. after the
+// replica has been repaired or replaced.
+func (s *Suspicions) Forgive(nodeID uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.offenses, nodeID)
+	delete(s.scores, nodeID)
+}
+
+// Less sorts nodes by their suspicion score in increasing order. It can be
+// passed to OrderedBy, e.g. OrderedBy(s.Less, Latency).
+func (s *Suspicions) Less(n1, n2 *Node) bool {
+	return s.Score(n1.id) < s.Score(n2.id)
+}
+
+// QuorumSpec returns a quorum specification that uses the quorum functions
+// of aq, and that records the offenses of the replicas observed by the
+// quorum calls issued by ReadRegister and WriteNext.
+//
+// A replica that returns a value signed for a writer ID with a timestamp
+// newer than the values signed by the quorum specification for that writer
+// ID is suspected of a contradiction. The timestamps signed are kept in
+// memory by the quorum specification, so the check assumes that no other
+// process signs values with the same writer ID, including earlier runs of
+// the same client whose writes may still be in progress.
+func (s *Suspicions) QuorumSpec(aq *AuthDataQ) NodeQuorumSpec {
+	return &suspectingQSpec{AuthDataQ: aq, suspicions: s, written: make(map[writerKey]int64)}
+}
+
+// NewTrustedConfiguration returns a configuration of size nodes using qspec.
+// Nodes suspected by s are excluded, and the remaining nodes are chosen in
+// order of increasing suspicion score, so that the least suspected nodes are
+// preferred. An error is returned if fewer than size nodes are trusted.
+func (m *Manager) NewTrustedConfiguration(size int, qspec QuorumSpec, s *Suspicions) (*Configuration, error) {
+	var trusted []*Node
+	for _, node := range m.Nodes() {
+		if !s.Suspected(node.id) {
+			trusted = append(trusted, node)
+		}
+	}
+	if len(trusted) < size {
+		return nil, IllegalConfigError(fmt.Sprintf("need %d nodes, only %d are trusted", size, len(trusted)))
+	}
+	OrderedBy(s.Less, ID).Sort(trusted)
+	ids := make([]uint32, size)
+	for i := range ids {
+		ids[i] = trusted[i].id
+	}
+	return m.NewConfiguration(ids, qspec)
+}
+
+// suspectingQSpec records the offenses of the replicas whose replies are
+// passed to its node quorum functions.
+type suspectingQSpec struct {
+	*AuthDataQ
+	suspicions *Suspicions
+
+	mu      sync.Mutex
+	written map[writerKey]int64 // highest timestamp signed by this process
+}
+
+type writerKey struct {
+	key      string
+	writerID uint32
+}
+
+// Sign signs content, and remembers its timestamp to detect values that
+// were never written by this client.
+func (q *suspectingQSpec) Sign(content *Content) (*Value, error) {
+	v, err := q.AuthDataQ.Sign(content)
+	if err != nil {
+		return nil, err
+	}
+	k := writerKey{content.Key, content.WriterID}
+	q.mu.Lock()
+	if ts, found := q.written[k]; !found || content.Timestamp > ts {
+		q.written[k] = content.Timestamp
+	}
+	q.mu.Unlock()
+	return v, nil
+}
+
+func (q *suspectingQSpec) Unwrap() QuorumSpec {
+	return q.AuthDataQ
+}
+
+// ReadNodeQF returns nil and false until the supplied replies constitute a
+// Byzantine quorum of verified or empty replies, at which point the method
+// returns the content with the highest timestamp and true. Only the last
+// reply is verified; if its signature is invalid, its node is suspected and
+// the reply is removed by setting its value to nil. Equivocation and
+// quorums without a valid value are handled as by the read quorum functions
+// of AuthDataQ.
+func (q *suspectingQSpec) ReadNodeQF(replies []NodeValue) (*Content, bool, error) {
+	highest, valid, err := q.verifyLast(replies)
+	if valid <= q.q {
+		// not enough valid replies yet
+		return nil, false, nil
+	}
+	return ReadResult{
+		Quorum:  true,
+		Value:   highest,
+		Replies: len(replies),
+		Invalid: len(replies) - valid,
+		Err:     err,
+	}.QF()
+}
+
+// ReadTimestampNodeQF is like ReadNodeQF, but returns the zero timestamp if
+// no reply carries a value. Equivocating values have the same timestamp, so
+// either can be returned.
+func (q *suspectingQSpec) ReadTimestampNodeQF(replies []NodeValue) (*Content, bool) {
+	highest, valid, err := q.verifyLast(replies)
+	if valid <= q.q {
+		// not enough valid replies yet
+		return nil, false
+	}
+	if ee, ok := err.(*EquivocationError); ok {
+		highest = ee.Values[0]
+	}
+	if highest == nil {
+		return &Content{}, true
+	}
+	return highest.C, true
+}
+
+// verifyLast verifies the last reply, and returns the reply with the highest
+// content and the number of replies that have not been removed, or an
+// EquivocationError. Evidence is recorded if the last reply is in conflict
+// with the values written by this client or with an earlier reply.
+func (q *suspectingQSpec) verifyLast(replies []NodeValue) (highest *Value, valid int, err error) {
+	if len(replies) == 0 {
+		return nil, 0, nil
+	}
+	last := &replies[len(replies)-1]
+	if last.Value.GetC() != nil {
+		if q.verify(last.Value) {
+			q.checkEvidence(*last, replies[:len(replies)-1])
+		} else {
+			q.suspicions.Suspect(last.NodeID, InvalidSignature)
+			last.Value = nil
+		}
+	}
+	values := make([]*Value, 0, len(replies))
+	for _, r := range replies {
+		if r.Value != nil {
+			values = append(values, r.Value)
+		}
+	}
+	highest, err = q.highest(values)
+	return highest, len(values), err
+}
+
+// checkEvidence records evidence if the verified reply r holds a value this
+// client never wrote for its own writer ID, or a value that differs from an
+// earlier reply for the same key, timestamp and writer ID.
+func (q *suspectingQSpec) checkEvidence(r NodeValue, earlier []NodeValue) {
+	c := r.Value.C
+	q.mu.Lock()
+	written, found := q.written[writerKey{c.Key, c.WriterID}]
+	q.mu.Unlock()
+	if found && c.Timestamp > written {
+		q.suspicions.Suspect(r.NodeID, Contradiction)
+		q.record(Evidence{
+			Kind:    Unacknowledged,
+			NodeIDs: []uint32{r.NodeID},
+			Values:  []*Value{r.Value},
+			Written: written,
+		})
+	}
+	for _, e := range earlier {
+		if ec := e.Value.GetC(); !sameWrite(ec, c) || ec.Equal(c) {
+			continue
+		}
+		q.record(Evidence{
+			Kind:    Equivocation,
+			NodeIDs: []uint32{e.NodeID, r.NodeID},
+			Values:  []*Value{e.Value, r.Value},
+		})
+		return
+	}
+}
+
+func (q *suspectingQSpec) record(e Evidence) {
+	if q.suspicions.evidence == nil {
+		return
+	}
+	e.Time = time.Now()
+	if err := q.suspicions.evidence.Record(e); err != nil {
+		log.Printf("failed to record %v: %v", &e, err)
+	}
+}
+
+// WriteNodeQF is like WriteQF, but suspects the node of the last reply if it
+// acknowledges another timestamp or writer ID than that of req.
+func (q *suspectingQSpec) WriteNodeQF(req *Value, replies []NodeWriteResponse) (*WriteResponse, bool) {
+	if len(replies) == 0 {
+		return nil, false
+	}
+	last := replies[len(replies)-1]
+	if !acknowledges(last.WriteResponse, req) {
+		q.suspicions.Suspect(last.NodeID, Contradiction)
+	}
+	acks := make([]*WriteResponse, len(replies))
+	for i, r := range replies {
+		acks[i] = r.WriteResponse
+	}
+	return q.WriteQF(req, acks)
+}
@@ -0,0 +1,170 @@
+package byzq
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestSuspicions(t *testing.T) {
+	s := NewSuspicions(2)
+	s.Suspect(1, InvalidSignature)
+	s.Suspect(2, InvalidSignature)
+	s.Suspect(2, Contradiction)
+
+	for _, test := range []struct {
+		id        uint32
+		score     int
+		suspected bool
+	}{
+		{1, 1, false},
+		{2, 2, true},
+		{3, 0, false},
+	} {
+		if got := s.Score(test.id); got != test.score {
+			t.Errorf("node %d: got score %d, want %d", test.id, got, test.score)
+		}
+		if got := s.Suspected(test.id); got != test.suspected {
+			t.Errorf("node %d: got suspected %t, want %t", test.id, got, test.suspected)
+		}
+	}
+	offenses := s.Offenses(2)
+	if offenses[InvalidSignature] != 1 || offenses[Contradiction] != 1 {
+		t.Errorf("got offenses %v, want one of each", offenses)
+	}
+	s.Forgive(2)
+	if s.Score(2) != 0 || s.Suspected(2) {
+		t.Errorf("got score %d after forgiving, want 0", s.Score(2))
+	}
+	if NewSuspicions(0).Suspected(1) {
+		t.Error("got suspected node with zero threshold")
+	}
+	var nilSuspicions *Suspicions
+	nilSuspicions.Suspect(1, InvalidSignature)
+}
+
+func TestSuspectingQSpec(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := qspec.Sign(myVal2.C)
+	if err != nil {
+		t.Fatal("Failed to sign message")
+	}
+	forged := &Value{C: &Content{Key: "Winnie", Value: "Poop", Timestamp: 9}, Signature: signed.Signature}
+	s := NewSuspicions(1)
+	sq := s.QuorumSpec(qspec)
+
+	replies := []NodeValue{{1, signed}, {2, &Value{}}, {3, forged}}
+	for i := range replies {
+		if _, quorum, _ := sq.ReadNodeQF(replies[:i+1]); quorum {
+			t.Fatalf("got quorum with %d replies including a forged reply", i+1)
+		}
+	}
+	if !s.Suspected(3) || s.Suspected(1) || s.Suspected(2) {
+		t.Errorf("got suspicion scores %d, %d, %d, want only node 3 suspected", s.Score(1), s.Score(2), s.Score(3))
+	}
+	replies = append(replies, NodeValue{4, signed})
+	c, quorum, err := sq.ReadNodeQF(replies)
+	if !quorum || err != nil || c != signed.C {
+		t.Errorf("got %v, %t, %v, want %v, true, nil", c, quorum, err, signed.C)
+	}
+	c, quorum = sq.ReadTimestampNodeQF([]NodeValue{{1, &Value{}}, {2, &Value{}}, {4, &Value{}}})
+	if !quorum || c.GetTimestamp() != 0 {
+		t.Errorf("got %v, %t, want zero timestamp, true", c, quorum)
+	}
+
+	acks := []NodeWriteResponse{
+		{1, &WriteResponse{Timestamp: 2}},
+		{2, &WriteResponse{Timestamp: 3}},
+		{3, &WriteResponse{Timestamp: 2, WriterID: 7}},
+	}
+	for i := range acks {
+		sq.WriteNodeQF(signed, acks[:i+1])
+	}
+	if s.Offenses(2)[Contradiction] != 1 || s.Offenses(3)[Contradiction] != 1 || s.Suspected(1) {
+		t.Errorf("got offenses %v, %v, want contradiction of nodes 2 and 3 only", s.Offenses(2), s.Offenses(3))
+	}
+}
+
+func TestNewTrustedConfiguration(t *testing.T) {
+	mgr, err := NewManager([]string{"127.0.0.1:9080", "127.0.0.1:9081", "127.0.0.1:9082"}, WithNoConnect())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := mgr.NodeIDs()
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSuspicions(2)
+	s.Suspect(ids[0], InvalidSignature)
+	config, err := mgr.NewTrustedConfiguration(2, qspec, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range config.NodeIDs() {
+		if id == ids[0] {
+			t.Errorf("got configuration %v with deprioritized node %d", config.NodeIDs(), id)
+		}
+	}
+	if _, err := mgr.NewTrustedConfiguration(3, qspec, s); err != nil {
+		t.Errorf("got error %v, want configuration including deprioritized node", err)
+	}
+
+	s.Suspect(ids[0], Contradiction)
+	if _, err := mgr.NewTrustedConfiguration(3, qspec, s); err == nil {
+		t.Error("got nil error for configuration including suspected node")
+	}
+}
+
+func TestSuspicionsCluster(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSuspicions(1)
+	// Server 0 flips bits in its signatures, while the late server 1 ensures
+	// that a quorum is only found after the reply of server 0 is received.
+	faulty := func(i int) []ServerOption {
+		switch i {
+		case 0:
+			return []ServerOption{WithFaults(BitFlip)}
+		case 1:
+			return []ServerOption{WithFaults(Late), WithFaultDelay(100 * time.Millisecond)}
+		}
+		return nil
+	}
+	config, stop := startServers(t, 4, s.QuorumSpec(qspec), faulty)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := config.WriteNext(ctx, 1, "Winnie", "Poo"); err != nil {
+		t.Fatal(err)
+	}
+	c, err := config.ReadRegister(ctx, &Key{Key: "Winnie"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.GetValue() != "Poo" {
+		t.Errorf("got %v, want value %q", c, "Poo")
+	}
+
+	var suspected []uint32
+	for _, id := range config.NodeIDs() {
+		if s.Suspected(id) {
+			suspected = append(suspected, id)
+		}
+	}
+	if len(suspected) != 1 {
+		t.Fatalf("got suspected nodes %v, want one", suspected)
+	}
+	// the reply to ReadTimestamp was empty, since no value was stored
+	if got := s.Offenses(suspected[0])[InvalidSignature]; got != 1 {
+		t.Errorf("got %d invalid signatures, want 1", got)
+	}
+}
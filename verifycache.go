@@ -0,0 +1,158 @@
+package byzq
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+)
+
+// VerifyCache is a Verifier that caches the results of another Verifier,
+// so that a signed value replied by several replicas, or passed to the read
+// quorum function again as more replies arrive, is only verified once.
+// Results are keyed by a digest of the signed message and the signature, and
+// the least recently used result is evicted when the cache is full.
+// A VerifyCache is safe for concurrent use.
+type VerifyCache struct {
+	verifier Verifier
+	size     int
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	lru     *list.List // front is most recently used
+	hits    uint64
+	misses  uint64
+}
+
+type verifyCacheEntry struct {
+	digest [sha256.Size]byte
+	valid  bool
+}
+
+// NewVerifyCache returns a cache of at most size results of verifier.
+func NewVerifyCache(verifier Verifier, size int) *VerifyCache {
+	return &VerifyCache{
+		verifier: verifier,
+		size:     size,
+		entries:  make(map[[sha256.Size]byte]*list.Element, size),
+		lru:      list.New(),
+	}
+}
+
+// Algorithm returns the signature algorithm of the cached verifier.
+func (c *VerifyCache) Algorithm() Algorithm {
+	return c.verifier.Algorithm()
+}
+
+// Verify reports whether sig is a valid signature of msg, using the cached
+// result if there is one.
+func (c *VerifyCache) Verify(msg, sig []byte) bool {
+	return c.verify(c.verifier, msg, sig)
+}
+
+// verify is Verify using verifier instead of the cached verifier. Since the
+// signed message of a value holds its writer ID, the results of different
+// writers of a WriterRegistry can share a cache.
+func (c *VerifyCache) verify(verifier Verifier, msg, sig []byte) bool {
+	digest := verifyDigest(msg, sig)
+	c.mu.Lock()
+	if e, found := c.entries[digest]; found {
+		c.lru.MoveToFront(e)
+		c.hits++
+		valid := e.Value.(*verifyCacheEntry).valid
+		c.mu.Unlock()
+		return valid
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// verify without holding the lock, so that concurrent misses are
+	// verified in parallel
+	valid := verifier.Verify(msg, sig)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, found := c.entries[digest]; found {
+		return valid
+	}
+	if c.lru.Len() >= c.size {
+		oldest := c.lru.Back()
+		if oldest == nil {
+			// size is zero; nothing is cached
+			return valid
+		}
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*verifyCacheEntry).digest)
+	}
+	c.entries[digest] = c.lru.PushFront(&verifyCacheEntry{digest: digest, valid: valid})
+	return valid
+}
+
+// verifyDigest returns the digest of msg and sig. The length of msg is
+// included so that no other split of the same bytes has the same digest.
+func verifyDigest(msg, sig []byte) [sha256.Size]byte {
+	h := sha256.New()
+	var n [8]byte
+	binary.LittleEndian.PutUint64(n[:], uint64(len(msg)))
+	h.Write(n[:])
+	h.Write(msg)
+	h.Write(sig)
+	var digest [sha256.Size]byte
+	h.Sum(digest[:0])
+	return digest
+}
+
+// VerifyCacheStats holds the statistics of a VerifyCache.
+type VerifyCacheStats struct {
+	Hits    uint64 // verifications answered by the cache
+	Misses  uint64 // verifications passed to the cached verifier
+	Entries int    // results currently cached
+}
+
+// HitRate returns the fraction of verifications answered by the cache.
+func (s VerifyCacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Stats returns the statistics of the cache.
+func (c *VerifyCache) Stats() VerifyCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return VerifyCacheStats{Hits: c.hits, Misses: c.misses, Entries: c.lru.Len()}
+}
+
+// SetVerifyCache enables a cache of at most size verification results,
+// which is shared by all read strategies and quorum calls using aq. A size
+// of zero or less disables the cache. It replaces any earlier cache. Since
+// the cache is read without synchronization by concurrent quorum calls and
+// VerifyPool workers, SetVerifyCache must only be called while setting up
+// aq, before it is used by quorum calls.
+func (aq *AuthDataQ) SetVerifyCache(size int) {
+	if c, ok := aq.verifier.(*VerifyCache); ok {
+		aq.verifier = c.verifier
+	}
+	if size > 0 {
+		aq.verifier = NewVerifyCache(aq.verifier, size)
+	}
+}
+
+// cachedWriter is the verifier of a writer of a WriterRegistry whose results
+// are cached in the verification cache of an AuthDataQ.
+type cachedWriter struct {
+	Verifier
+	cache *VerifyCache
+}
+
+func (w cachedWriter) Verify(msg, sig []byte) bool {
+	return w.cache.verify(w.Verifier, msg, sig)
+}
+
+// VerifyCache returns the verification cache enabled by SetVerifyCache, or
+// nil if it is disabled.
+func (aq *AuthDataQ) VerifyCache() *VerifyCache {
+	c, _ := aq.verifier.(*VerifyCache)
+	return c
+}
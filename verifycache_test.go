@@ -0,0 +1,148 @@
+package byzq
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// countingVerifier counts the verifications passed to a Verifier.
+type countingVerifier struct {
+	Verifier
+	n uint64
+}
+
+func (v *countingVerifier) Verify(msg, sig []byte) bool {
+	atomic.AddUint64(&v.n, 1)
+	return v.Verifier.Verify(msg, sig)
+}
+
+func TestVerifyCache(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := &countingVerifier{Verifier: qspec.verifier}
+	c := NewVerifyCache(verifier, 2)
+
+	sign := func(value string) ([]byte, []byte) {
+		v := signContent(t, qspec, "Winnie", value, 1, 0)
+		msg, err := v.C.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msg, v.Signature
+	}
+	msgA, sigA := sign("Poo")
+	msgB, sigB := sign("Tigger")
+
+	verifyTests := []struct {
+		name      string
+		msg, sig  []byte
+		valid     bool
+		verifiers uint64 // calls to the cached verifier so far
+	}{
+		{"miss", msgA, sigA, true, 1},
+		{"hit", msgA, sigA, true, 1},
+		{"forged miss", msgB, sigA, false, 2},
+		{"forged hit", msgB, sigA, false, 2},
+		{"recently used", msgA, sigA, true, 2},
+		{"evict least recently used", msgB, sigB, true, 3},
+		{"not evicted", msgA, sigA, true, 3},
+		{"evicted", msgB, sigA, false, 4},
+		{"evict again", msgB, sigB, true, 5},
+		{"hit after eviction", msgB, sigA, false, 5},
+	}
+	for _, test := range verifyTests {
+		if valid := c.Verify(test.msg, test.sig); valid != test.valid {
+			t.Errorf("%s: got %t, want %t", test.name, valid, test.valid)
+		}
+		if n := atomic.LoadUint64(&verifier.n); n != test.verifiers {
+			t.Errorf("%s: got %d verifications, want %d", test.name, n, test.verifiers)
+		}
+	}
+	stats := c.Stats()
+	if stats.Hits != 5 || stats.Misses != 5 || stats.Entries != 2 {
+		t.Errorf("got %+v, want 5 hits, 5 misses and 2 entries", stats)
+	}
+	if rate := stats.HitRate(); rate != 0.5 {
+		t.Errorf("got hit rate %v, want 0.5", rate)
+	}
+
+	empty := NewVerifyCache(verifier, 0)
+	empty.Verify(msgA, sigA)
+	empty.Verify(msgA, sigA)
+	if stats := empty.Stats(); stats.Hits != 0 || stats.Entries != 0 {
+		t.Errorf("got %+v, want no hits or entries with size 0", stats)
+	}
+}
+
+func TestVerifyCacheConcurrent(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qspec.SetVerifyCache(4)
+	var values []*Value
+	for _, value := range []string{"Poo", "Tigger", "Eeyore", "Piglet", "Roo", "Kanga"} {
+		values = append(values, signContent(t, qspec, "Winnie", value, 1, 0))
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if v := values[(i+j)%len(values)]; !qspec.verify(v) {
+					t.Errorf("got invalid signature for %v", v.C)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if stats := qspec.VerifyCache().Stats(); stats.Hits+stats.Misses != 400 || stats.Entries > 4 {
+		t.Errorf("got %+v, want 400 verifications and at most 4 entries", stats)
+	}
+}
+
+func TestSetVerifyCache(t *testing.T) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := qspec.VerifyCache(); c != nil {
+		t.Fatalf("got cache %v, want none by default", c)
+	}
+	qspec.SetVerifyCache(16)
+	qspec.SetVerifyCache(16)
+	c := qspec.VerifyCache()
+	if c == nil {
+		t.Fatal("got no cache, want cache")
+	}
+	if _, nested := c.verifier.(*VerifyCache); nested {
+		t.Error("got nested caches, want earlier cache replaced")
+	}
+
+	v := signContent(t, qspec, "Winnie", "Poo", 1, 0)
+	forged := &Value{C: &Content{Key: "Winnie", Value: "Tigger", Timestamp: 2}, Algorithm: v.Algorithm, Signature: v.Signature}
+	replies := []*Value{v, forged, v, v}
+	for _, s := range readStrategies {
+		replies := append([]*Value(nil), replies...)
+		var r ReadResult
+		for i := 1; i <= len(replies) && !r.Quorum; i++ {
+			r = s.Evaluate(qspec, replies[:i])
+		}
+		if got, _, err := r.QF(); err != nil || !got.Equal(v.C) {
+			t.Errorf("%v: got %v, %v, want %v, nil", s, got, err, v.C)
+		}
+	}
+	// only v and forged are verified by the cached verifier
+	if stats := c.Stats(); stats.Misses != 2 || stats.Entries != 2 {
+		t.Errorf("got %+v, want 2 misses and 2 entries", stats)
+	}
+
+	qspec.SetVerifyCache(0)
+	if c := qspec.VerifyCache(); c != nil {
+		t.Errorf("got cache %v, want none after disabling", c)
+	}
+}
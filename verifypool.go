@@ -0,0 +1,96 @@
+package byzq
+
+import (
+	"runtime"
+	"sync"
+)
+
+// VerifyPool is a ReadStrategy that verifies replies concurrently using a
+// fixed number of worker goroutines shared by all quorum calls and quorum
+// specifications using the pool. Verifications are queued in a bounded
+// queue, and Evaluate blocks while the queue is full, so that the number of
+// goroutines and pending verifications stays bounded regardless of the
+// number of concurrent quorum calls.
+type VerifyPool struct {
+	workers int
+	once    sync.Once
+	jobs    chan verifyJob
+}
+
+type verifyJob struct {
+	aq      *AuthDataQ
+	reply   *Value
+	index   int
+	results chan<- verifyResult
+}
+
+type verifyResult struct {
+	index int
+	valid bool
+}
+
+// PoolVerify verifies replies using a pool of GOMAXPROCS workers shared by
+// all quorum specifications using this strategy.
+var PoolVerify ReadStrategy = NewVerifyPool(runtime.GOMAXPROCS(0))
+
+// NewVerifyPool returns a pool of the given number of workers, and a queue
+// of the same size. The workers are started when the pool is first used.
+func NewVerifyPool(workers int) *VerifyPool {
+	if workers < 1 {
+		workers = 1
+	}
+	return &VerifyPool{workers: workers}
+}
+
+func (p *VerifyPool) start() {
+	p.jobs = make(chan verifyJob, p.workers)
+	for i := 0; i < p.workers; i++ {
+		go func() {
+			for job := range p.jobs {
+				job.results <- verifyResult{job.index, job.aq.verify(job.reply)}
+			}
+		}()
+	}
+}
+
+// Close stops the workers of the pool. The pool must not be used after
+// Close.
+func (p *VerifyPool) Close() {
+	p.once.Do(p.start)
+	close(p.jobs)
+}
+
+func (p *VerifyPool) String() string { return "pool" }
+
+// Evaluate verifies the replies using the workers of the pool.
+func (p *VerifyPool) Evaluate(aq *AuthDataQ, replies []*Value) ReadResult {
+	if len(replies) <= aq.q {
+		// not enough replies yet; need at least bq.q=(n+2f)/2 replies
+		return ReadResult{Replies: len(replies)}
+	}
+	p.once.Do(p.start)
+
+	// results is large enough that the workers never block on it, so the
+	// jobs can be queued before any result is received
+	results := make(chan verifyResult, len(replies))
+	queued := 0
+	for i, reply := range replies {
+		if reply.GetC() == nil {
+			continue
+		}
+		p.jobs <- verifyJob{aq: aq, reply: reply, index: i, results: results}
+		queued++
+	}
+	valid := make([]bool, len(replies))
+	for ; queued > 0; queued-- {
+		r := <-results
+		valid[r.index] = r.valid
+	}
+	verified := make([]*Value, 0, len(replies))
+	for i, v := range valid {
+		if v {
+			verified = append(verified, replies[i])
+		}
+	}
+	return aq.result(replies, verified)
+}
@@ -0,0 +1,98 @@
+package byzq
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// blockingVerifier blocks verifications until released, and records the
+// highest number of concurrent verifications.
+type blockingVerifier struct {
+	Verifier
+	release chan struct{}
+
+	mu      sync.Mutex
+	running int
+	max     int
+}
+
+func (v *blockingVerifier) Verify(msg, sig []byte) bool {
+	v.mu.Lock()
+	v.running++
+	if v.running > v.max {
+		v.max = v.running
+	}
+	v.mu.Unlock()
+	<-v.release
+	v.mu.Lock()
+	v.running--
+	v.mu.Unlock()
+	return v.Verifier.Verify(msg, sig)
+}
+
+func TestVerifyPool(t *testing.T) {
+	signer, err := NewSigner(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := NewVerifier(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocking := &blockingVerifier{Verifier: verifier, release: make(chan struct{})}
+	qspec, err := NewAuthDataQFromScheme(4, signer, blocking)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := signContent(t, qspec, "Winnie", "Poo", 1, 0)
+
+	const workers = 2
+	pool := NewVerifyPool(workers)
+	defer pool.Close()
+	qspec.SetReadStrategy(pool)
+
+	// many concurrent quorum calls share the workers of the pool
+	const calls = 8
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c, quorum, err := qspec.ReadQF([]*Value{v, v, v, v}); !quorum || err != nil || !c.Equal(v.C) {
+				t.Errorf("got %v, %t, %v, want %v, true, nil", c, quorum, err, v.C)
+			}
+		}()
+	}
+	for i := 0; i < calls*4; i++ {
+		blocking.release <- struct{}{}
+	}
+	wg.Wait()
+	if blocking.max > workers {
+		t.Errorf("got %d concurrent verifications, want at most %d", blocking.max, workers)
+	}
+}
+
+func BenchmarkConcurrentReadQF(b *testing.B) {
+	qspec, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		b.Fatal(err)
+	}
+	v, err := qspec.Sign(myVal.C)
+	if err != nil {
+		b.Fatal("Failed to sign message")
+	}
+
+	// many clients issuing quorum calls at the same time
+	for _, s := range []ReadStrategy{SequentialVerify, ConcurrentVerifyWG, ConcurrentVerifyIndexChan, PoolVerify} {
+		b.Run(fmt.Sprintf("%v(4,1)", s), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetParallelism(16)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					s.Evaluate(qspec, []*Value{v, v, v, v})
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package byzq
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+)
+
+// signer is implemented by quorum specifications that hold the writer's
+// private key and can sign content before it is written.
+type signer interface {
+	Sign(content *Content) (*Value, error)
+}
+
+// WriteNext writes value to key using the two-phase multi-writer protocol.
+// The first phase invokes ReadTimestamp to learn the highest verified timestamp
+// stored by a quorum. The second phase writes the value with timestamp ts+1
+// and the provided writerID, which totally orders writes from concurrent
+// writers that picked the same timestamp.
+// The configuration's quorum specification is used to sign the value. If it
+// is a NodeQuorumSpec, its node quorum functions are used.
+func (c *Configuration) WriteNext(ctx context.Context, writerID uint32, key, value string) (*WriteResponse, error) {
+	s, ok := c.qspec.(signer)
+	if !ok {
+		return nil, fmt.Errorf("quorum specification of %v cannot sign values", c)
+	}
+	highest, err := c.nodeReadTimestamp(ctx, &Key{Key: key})
+	if err != nil {
+		return nil, err
+	}
+	signed, err := s.Sign(&Content{
+		Key:       key,
+		Value:     value,
+		Timestamp: highest.GetTimestamp() + 1,
+		WriterID:  writerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.nodeWrite(ctx, signed)
+}
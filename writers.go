@@ -0,0 +1,103 @@
+package byzq
+
+import (
+	"fmt"
+	"sync"
+)
+
+// WriterRegistry holds the verifiers of the writers whose values are
+// accepted, by writer ID. A value is only valid if it is signed by the
+// writer whose ID it holds, so that a writer cannot claim the ID of another
+// writer to win ties between concurrent writes. It is safe for concurrent
+// use, so that writers can be added while a replica is serving. A nil registry
+// holds no writers.
+type WriterRegistry struct {
+	mu      sync.RWMutex
+	writers map[uint32]Verifier
+}
+
+// NewWriterRegistry returns a registry holding the given writers, where
+// writers[i] is the verifier of the writer with ID i.
+func NewWriterRegistry(writers ...Verifier) *WriterRegistry {
+	r := &WriterRegistry{writers: make(map[uint32]Verifier, len(writers))}
+	for id, writer := range writers {
+		r.writers[uint32(id)] = writer
+	}
+	return r
+}
+
+// ReadWriterRegistry returns a registry holding the writers whose public keys
+// are stored in the given PEM files, where pubFiles[i] holds the key of the
+// writer with ID i.
+func ReadWriterRegistry(pubFiles ...string) (*WriterRegistry, error) {
+	r := NewWriterRegistry()
+	for id, pubFile := range pubFiles {
+		pub, err := ReadPublicKeyfile(pubFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading writer key %s: %v", pubFile, err)
+		}
+		verifier, err := NewVerifier(pub)
+		if err != nil {
+			return nil, fmt.Errorf("error reading writer key %s: %v", pubFile, err)
+		}
+		if err := r.Add(uint32(id), verifier); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Add adds the writer with the given ID to the registry, or returns an error
+// if the registry already holds a writer with that ID. Writers cannot be
+// replaced, since values verified with the replaced key would remain valid
+// in verification caches.
+func (r *WriterRegistry) Add(id uint32, writer Verifier) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, found := r.writers[id]; found {
+		return fmt.Errorf("writer %d is already registered", id)
+	}
+	r.writers[id] = writer
+	return nil
+}
+
+// Len returns the number of writers in the registry.
+func (r *WriterRegistry) Len() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.writers)
+}
+
+// Verifier returns the verifier of the writer with the given ID, if any.
+func (r *WriterRegistry) Verifier(id uint32) (Verifier, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	verifier, found := r.writers[id]
+	return verifier, found
+}
+
+// Verify reports whether v is signed by the writer whose ID it holds.
+func (r *WriterRegistry) Verify(v *Value) bool {
+	verifier, found := r.Verifier(v.GetC().GetWriterID())
+	return found && Verify(verifier, v)
+}
+
+// SetWriters makes aq verify each value with the verifier of the writer whose
+// ID the value holds in writers, instead of with the verifier aq was created
+// with, so that readers accept the values of all registered writers. A nil
+// registry restores the verifier aq was created with. Since results of the
+// earlier verifiers no longer apply, the verification cache, if enabled, is
+// replaced by an empty one. Like the other settings of aq, it must not be
+// changed while aq is used by quorum calls.
+func (aq *AuthDataQ) SetWriters(writers *WriterRegistry) {
+	aq.writers = writers
+	if c := aq.VerifyCache(); c != nil {
+		aq.SetVerifyCache(c.size)
+	}
+}
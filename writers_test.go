@@ -0,0 +1,78 @@
+package byzq
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+)
+
+// newTestWriters returns the quorum specifications of writers 0, signing
+// with priv, and 1, signing with a key of its own, and a registry of both.
+func newTestWriters(t *testing.T) (*AuthDataQ, *AuthDataQ, *WriterRegistry) {
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer0, err := NewAuthDataQ(4, priv, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer1, err := NewAuthDataQ(4, other, &other.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writer0, writer1, NewWriterRegistry(writer0.verifier, writer1.verifier)
+}
+
+func TestWriterRegistry(t *testing.T) {
+	writer0, writer1, writers := newTestWriters(t)
+	if err := writers.Add(1, writer0.verifier); err == nil {
+		t.Error("got nil error when replacing writer 1")
+	}
+
+	verifyTests := []struct {
+		name  string
+		v     *Value
+		valid bool
+	}{
+		{"writer 0", signContent(t, writer0, "Winnie", "Poo", 1, 0), true},
+		{"writer 1", signContent(t, writer1, "Winnie", "Poo", 1, 1), true},
+		{"writer 0 as writer 1", signContent(t, writer0, "Winnie", "Poo", 1, 1), false},
+		{"writer 1 as writer 0", signContent(t, writer1, "Winnie", "Poo", 1, 0), false},
+		{"unknown writer", signContent(t, writer0, "Winnie", "Poo", 1, 2), false},
+		{"nil value", nil, false},
+	}
+	for _, test := range verifyTests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := writers.Verify(test.v); got != test.valid {
+				t.Errorf("got %t, want %t", got, test.valid)
+			}
+		})
+	}
+}
+
+func TestSetWriters(t *testing.T) {
+	writer0, writer1, writers := newTestWriters(t)
+	v := signContent(t, writer1, "Winnie", "Tigger", 2, 1)
+	claimed := signContent(t, writer0, "Winnie", "Tigger", 2, 1)
+
+	for _, cache := range []int{0, 16} {
+		reader, err := NewReadOnlyAuthDataQ(4, &priv.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader.SetVerifyCache(cache)
+		if _, quorum, _ := reader.ReadQF([]*Value{v, v, v}); quorum {
+			t.Errorf("cache %d: got quorum for writer 1 without the writer registry", cache)
+		}
+		reader.SetWriters(writers)
+		c, quorum, err := reader.ReadQF([]*Value{v, v, v})
+		if !quorum || err != nil || c != v.C {
+			t.Errorf("cache %d: got %v, %t, %v, want %v, true, nil", cache, c, quorum, err, v.C)
+		}
+		if c, quorum, _ := reader.ReadQF([]*Value{claimed, claimed, claimed}); quorum && c != nil {
+			t.Errorf("cache %d: got %v for a value of writer 0 claiming writer 1", cache, c)
+		}
+	}
+}